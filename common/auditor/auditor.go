@@ -0,0 +1,20 @@
+// Package auditor 在 context.Context 中传递当前操作人，用于自动填充审计字段
+package auditor
+
+import "context"
+
+type auditorKey struct{}
+
+// WithAuditor 返回携带操作人的 context
+func WithAuditor(ctx context.Context, auditor string) context.Context {
+	return context.WithValue(ctx, auditorKey{}, auditor)
+}
+
+// FromContext 获取 context 中的操作人
+func FromContext(ctx context.Context) (string, bool) {
+	if nil == ctx {
+		return "", false
+	}
+	v, ok := ctx.Value(auditorKey{}).(string)
+	return v, ok && "" != v
+}
@@ -3,3 +3,12 @@ package common
 import "errors"
 
 var ErrNoResult = errors.New("no result")
+
+// ErrOptimisticLock 乐观锁冲突：数据已被并发修改或删除
+var ErrOptimisticLock = errors.New("optimistic lock conflict")
+
+// ErrTenantRequired 多租户模式下 context 中缺少租户 ID
+var ErrTenantRequired = errors.New("tenant required")
+
+// ErrCrossTenant 访问或写入其他租户的数据
+var ErrCrossTenant = errors.New("cross-tenant access")
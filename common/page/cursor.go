@@ -0,0 +1,109 @@
+package page
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/aomi-go/data/common/sort"
+)
+
+// ErrInvalidCursor 游标格式错误、签名不匹配或与当前排序不一致
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func NewCursorPageable(size int, cursor string) *CursorPageable {
+	return &CursorPageable{
+		Size:   &size,
+		Cursor: cursor,
+	}
+}
+
+func NewCursorPageableWithSort(size int, cursor string, s sort.Sort) *CursorPageable {
+	return &CursorPageable{
+		Sort:   s,
+		Size:   &size,
+		Cursor: cursor,
+	}
+}
+
+// CursorPageable 游标分页请求，Cursor 为空时返回第一页，否则为上一次返回的 NextCursor 或 PreviousCursor
+type CursorPageable struct {
+	sort.Sort
+	Size   *int   `form:"size" json:"size" describe:"每页的大小"`
+	Cursor string `form:"cursor" json:"cursor" describe:"游标"`
+}
+
+func (p *CursorPageable) GetSize() int {
+	if p.Size == nil || *p.Size <= 0 {
+		return 20
+	}
+	return *p.Size
+}
+
+// CursorPage 游标分页结果
+type CursorPage[T interface{}] struct {
+	Empty            bool   `json:"empty"`
+	NumberOfElements int    `json:"numberOfElements"`
+	Size             int    `json:"size"`
+	HasNext          bool   `json:"hasNext"`
+	HasPrevious      bool   `json:"hasPrevious"`
+	NextCursor       string `json:"nextCursor,omitempty"`
+	PreviousCursor   string `json:"previousCursor,omitempty"`
+	Content          []*T   `json:"content"`
+	Extra            any    `json:"extra" describe:"额外信息"`
+}
+
+func NewCursorPage[T interface{}](content []*T, size int, next string, previous string) *CursorPage[T] {
+	return &CursorPage[T]{
+		Empty:            len(content) == 0,
+		NumberOfElements: len(content),
+		Size:             size,
+		HasNext:          "" != next,
+		HasPrevious:      "" != previous,
+		NextCursor:       next,
+		PreviousCursor:   previous,
+		Content:          content,
+	}
+}
+
+// NewCursorCodec 创建使用 HMAC-SHA256 签名的游标编解码器
+func NewCursorCodec(secret []byte) *CursorCodec {
+	return &CursorCodec{secret: append([]byte{}, secret...)}
+}
+
+// CursorCodec 将游标内容编码为不透明且防篡改的字符串
+type CursorCodec struct {
+	secret []byte
+}
+
+// Encode 编码游标内容
+func (c *CursorCodec) Encode(payload []byte) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload))
+}
+
+// Decode 校验签名并返回游标内容，失败时返回 ErrInvalidCursor
+func (c *CursorCodec) Decode(cursor string) ([]byte, error) {
+	enc := base64.RawURLEncoding
+	data, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,22 @@
+package page
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorCodec(t *testing.T) {
+	codec := NewCursorCodec([]byte("secret"))
+	cursor := codec.Encode([]byte("payload"))
+
+	payload, err := codec.Decode(cursor)
+	if err != nil || string(payload) != "payload" {
+		t.Fatalf("Decode() = %q, %v", payload, err)
+	}
+
+	for _, c := range []string{"", "payload", cursor + "x", "cGF5bG9hZA." + cursor[len(cursor)-10:], NewCursorCodec([]byte("other")).Encode([]byte("payload"))} {
+		if _, err := codec.Decode(c); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Decode(%q) error = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+}
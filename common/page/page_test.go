@@ -7,27 +7,28 @@ import (
 
 func TestPage(t *testing.T) {
 
-	var content []string
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
-	content = append(content, "1")
+	one := "1"
+	var content []*string
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
+	content = append(content, &one)
 
 	page := NewPage(content, 1000, nil)
 
@@ -0,0 +1,53 @@
+package page
+
+// NewSlice 创建分片，hasNext 表示是否还有下一页
+func NewSlice[T interface{}](content []*T, hasNext bool, pageable *Pageable) *Slice[T] {
+	if nil == pageable {
+		pageable = NewDefaultPageable()
+	}
+	return &Slice[T]{
+		Empty:            len(content) == 0,
+		First:            pageable.GetPage() == 0,
+		Last:             !hasNext,
+		Number:           pageable.GetPage(),
+		NumberOfElements: len(content),
+		Size:             pageable.GetSize(),
+		HasNext:          hasNext,
+		Content:          content,
+	}
+}
+
+// Slice 不包含总数的分页结果，字段命名与 Page 一致，适用于只需要判断是否还有下一页的场景
+type Slice[T interface{}] struct {
+	Empty            bool `json:"empty"`
+	First            bool `json:"first"`
+	Last             bool `json:"last"`
+	Number           int  `json:"number"`
+	NumberOfElements int  `json:"numberOfElements"`
+	Size             int  `json:"size"`
+	HasNext          bool `json:"hasNext"`
+	Content          []*T `json:"content"`
+	Extra            any  `json:"extra" describe:"额外信息"`
+}
+
+// MapSlice 转换slice类型
+func MapSlice[T interface{}, N interface{}](old *Slice[T], mapfunc func(t *T) *N) *Slice[N] {
+	var newSlice = Slice[N]{
+		Empty:            old.Empty,
+		First:            old.First,
+		Last:             old.Last,
+		Number:           old.Number,
+		NumberOfElements: old.NumberOfElements,
+		Size:             old.Size,
+		HasNext:          old.HasNext,
+		Extra:            old.Extra,
+	}
+
+	var content = make([]*N, len(old.Content))
+	for i, v := range old.Content {
+		content[i] = mapfunc(v)
+	}
+	newSlice.Content = content
+
+	return &newSlice
+}
@@ -0,0 +1,28 @@
+package page
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	a, b := "a", "b"
+	s := NewSlice([]*string{&a, &b}, true, NewPageable(1, 2))
+	if s.First || s.Last || !s.HasNext || s.Number != 1 || s.NumberOfElements != 2 || s.Size != 2 {
+		t.Fatalf("NewSlice() = %+v", s)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"empty":false,"first":false,"last":false,"number":1,"numberOfElements":2,"size":2,"hasNext":true,"content":["a","b"],"extra":null}`
+	if string(data) != want {
+		t.Errorf("json = %s, want %s", data, want)
+	}
+
+	lengths := MapSlice(s, func(v *string) *int { n := len(*v); return &n })
+	if *lengths.Content[1] != 1 || !lengths.HasNext {
+		t.Errorf("MapSlice() = %+v", lengths)
+	}
+}
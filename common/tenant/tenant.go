@@ -0,0 +1,36 @@
+// Package tenant 在 context.Context 中传递当前租户，用于多租户数据隔离
+package tenant
+
+import "context"
+
+type tenantKey struct{}
+
+type systemKey struct{}
+
+// WithTenant 返回携带租户 ID 的 context
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantId)
+}
+
+// FromContext 获取 context 中的租户 ID
+func FromContext(ctx context.Context) (string, bool) {
+	if nil == ctx {
+		return "", false
+	}
+	v, ok := ctx.Value(tenantKey{}).(string)
+	return v, ok && "" != v
+}
+
+// WithSystem 返回系统 context，系统 context 不受租户隔离限制，可以访问全部租户的数据
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemKey{}, true)
+}
+
+// IsSystem 是否为系统 context
+func IsSystem(ctx context.Context) bool {
+	if nil == ctx {
+		return false
+	}
+	v, _ := ctx.Value(systemKey{}).(bool)
+	return v
+}
@@ -0,0 +1,28 @@
+package mongoxcodec
+
+import (
+	"reflect"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+)
+
+// NewRegistry 创建注册了 decimal.Decimal 与 mongoxentity.StrObjectId 编解码器的 Registry
+func NewRegistry() *bsoncodec.Registry {
+	registry := bson.NewRegistry()
+	Register(registry)
+	return registry
+}
+
+// Register 向已有的 Registry 注册本包提供的编解码器
+func Register(registry *bsoncodec.Registry) {
+	decimalType := reflect.TypeOf(decimal.Decimal{})
+	registry.RegisterTypeEncoder(decimalType, &DecimalEncoder{})
+	registry.RegisterTypeDecoder(decimalType, &DecimalDecoder{})
+
+	strIdType := reflect.TypeOf(mongoxentity.StrObjectId(""))
+	registry.RegisterTypeEncoder(strIdType, &StrObjectIdEncoder{})
+	registry.RegisterTypeDecoder(strIdType, &StrObjectIdDecoder{})
+}
@@ -0,0 +1,55 @@
+package mongoxentity
+
+import "time"
+
+// TimeAuditable 由 DocumentRepository 在保存时自动填充创建时间与更新时间
+type TimeAuditable interface {
+	GetCreatedAt() time.Time
+	SetCreatedAt(t time.Time)
+	SetUpdatedAt(t time.Time)
+}
+
+// Auditable 在 TimeAuditable 的基础上自动填充创建人与更新人
+type Auditable interface {
+	TimeAuditable
+	GetCreatedBy() string
+	SetCreatedBy(by string)
+	SetUpdatedBy(by string)
+}
+
+// AuditTime 可嵌入实体的审计时间字段，嵌入时需要添加 bson:",inline"
+type AuditTime struct {
+	CreatedAt time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+func (a *AuditTime) GetCreatedAt() time.Time {
+	return a.CreatedAt
+}
+
+func (a *AuditTime) SetCreatedAt(t time.Time) {
+	a.CreatedAt = t
+}
+
+func (a *AuditTime) SetUpdatedAt(t time.Time) {
+	a.UpdatedAt = t
+}
+
+// AuditFields 可嵌入实体的完整审计字段，嵌入时需要添加 bson:",inline"
+type AuditFields struct {
+	AuditTime `bson:",inline"`
+	CreatedBy string `bson:"created_by,omitempty" json:"createdBy,omitempty"`
+	UpdatedBy string `bson:"updated_by,omitempty" json:"updatedBy,omitempty"`
+}
+
+func (a *AuditFields) GetCreatedBy() string {
+	return a.CreatedBy
+}
+
+func (a *AuditFields) SetCreatedBy(by string) {
+	a.CreatedBy = by
+}
+
+func (a *AuditFields) SetUpdatedBy(by string) {
+	a.UpdatedBy = by
+}
@@ -0,0 +1,23 @@
+package mongoxentity
+
+import "context"
+
+// BeforeSaveHook 在 Save、SaveMany 写入之前调用，返回错误将中止保存
+type BeforeSaveHook interface {
+	BeforeSave(ctx context.Context) error
+}
+
+// AfterSaveHook 在 Save、SaveMany 写入成功之后调用
+type AfterSaveHook interface {
+	AfterSave(ctx context.Context) error
+}
+
+// AfterLoadHook 在查询结果解码之后调用
+type AfterLoadHook interface {
+	AfterLoad(ctx context.Context) error
+}
+
+// BeforeDeleteHook 在 DeleteById、Delete 删除之前对每个待删除的实体调用，返回错误将中止删除
+type BeforeDeleteHook interface {
+	BeforeDelete(ctx context.Context) error
+}
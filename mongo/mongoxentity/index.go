@@ -0,0 +1,22 @@
+package mongoxentity
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Index 索引定义，Keys 为有序的字段与方向：1、-1、"text"、"2dsphere" 等
+type Index struct {
+	// Name 为空时使用 MongoDB 默认的命名规则，如 user_id_1_created_at_-1
+	Name   string
+	Keys   bson.D
+	Unique bool
+	Sparse bool
+	// ExpireAfterSeconds 不为空时为 TTL 索引
+	ExpireAfterSeconds *int32
+	// PartialFilter 部分索引的过滤条件
+	PartialFilter interface{}
+}
+
+// Indexed 实现该接口的实体声明自己的索引，与 mongox 标签声明的索引合并。
+// 标签无法表达的部分索引、复杂的复合索引通过该接口声明
+type Indexed interface {
+	Indexes() []Index
+}
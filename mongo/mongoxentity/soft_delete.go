@@ -0,0 +1,15 @@
+package mongoxentity
+
+import "time"
+
+// SoftDeleteFields 可嵌入实体的软删除标记字段，嵌入时需要添加 bson:",inline"。
+// 字段名与 DocumentRepository 软删除模式使用的 deleted_at、deleted_by 保持一致
+type SoftDeleteFields struct {
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"deletedBy,omitempty"`
+}
+
+// IsDeleted 是否已被软删除
+func (s *SoftDeleteFields) IsDeleted() bool {
+	return nil != s.DeletedAt
+}
@@ -0,0 +1,20 @@
+package mongoxentity
+
+// TenantAware 多租户实体，租户 ID 存储在 tenant_id 字段
+type TenantAware interface {
+	GetTenantId() string
+	SetTenantId(tenantId string)
+}
+
+// TenantFields 可嵌入实体的租户字段，嵌入时需要添加 bson:",inline"
+type TenantFields struct {
+	TenantId string `bson:"tenant_id" json:"tenantId"`
+}
+
+func (t *TenantFields) GetTenantId() string {
+	return t.TenantId
+}
+
+func (t *TenantFields) SetTenantId(tenantId string) {
+	t.TenantId = tenantId
+}
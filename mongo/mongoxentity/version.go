@@ -0,0 +1,21 @@
+package mongoxentity
+
+// Versioned 实现该接口的实体在保存时使用乐观锁，版本号存储在 version 字段。
+// 也可以在任意整数字段上添加 mongox:"version" 标签达到同样效果。
+type Versioned interface {
+	GetVersion() int64
+	SetVersion(version int64)
+}
+
+// Versioning 可嵌入实体的版本字段，嵌入时需要添加 bson:",inline"
+type Versioning struct {
+	Version int64 `bson:"version" json:"version" mongox:"version"`
+}
+
+func (v *Versioning) GetVersion() int64 {
+	return v.Version
+}
+
+func (v *Versioning) SetVersion(version int64) {
+	v.Version = version
+}
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/tenant"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/aomi-go/data/repository/memory"
+	"github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type user struct {
+	ID   mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Name string                   `bson:"name"`
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1, 0)
+	c.Set("b", 2, time.Second)
+	c.Get("a")
+	c.Set("c", 3, 0)
+	if _, ok := c.Get("b"); ok {
+		t.Fatalf("least recently used entry was not evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+
+	c.Set("d", 4, time.Second)
+	now = now.Add(2 * time.Second)
+	if _, ok := c.Get("d"); ok {
+		t.Fatalf("expired entry returned")
+	}
+	if c.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", c.Len())
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("deleted entry returned")
+	}
+}
+
+// counting 统计到达被包装仓储的读操作次数
+func counting(calls map[string]int) mongo.Interceptor {
+	return func(ctx context.Context, inv *mongo.Invocation, next mongo.Handler) error {
+		calls[inv.Operation]++
+		return next(ctx, inv)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.TODO()
+	calls := map[string]int{}
+	repo := NewRepository[user](mongo.Intercept[user](memory.NewRepository[user](), counting(calls)), NewLRU(100), time.Minute)
+
+	saved, err := repo.SaveMany(ctx, []*user{{Name: "a"}, {Name: "b"}})
+	if err != nil {
+		t.Fatalf("SaveMany() error = %v", err)
+	}
+	a, b := saved[0], saved[1]
+
+	for i := 0; i < 3; i++ {
+		found, err := repo.FindById(ctx, a.ID)
+		if err != nil || found.Name != "a" {
+			t.Fatalf("FindById() = %v, %v", found, err)
+		}
+		// 修改返回值不影响缓存
+		found.Name = "changed"
+	}
+	if calls[mongo.OpFindById] != 1 {
+		t.Fatalf("FindById reached repository %d times, want 1", calls[mongo.OpFindById])
+	}
+
+	all, err := repo.FindAllById(ctx, a.ID, b.ID)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("FindAllById() = %v, %v", all, err)
+	}
+	if _, err := repo.FindAllById(ctx, a.ID, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if calls[mongo.OpFindAllById] != 1 {
+		t.Fatalf("FindAllById reached repository %d times, want 1", calls[mongo.OpFindAllById])
+	}
+
+	a.Name = "a2"
+	if _, err := repo.Save(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repo.FindById(ctx, a.ID)
+	if err != nil || found.Name != "a2" {
+		t.Fatalf("FindById() after Save = %v, %v", found, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		named, err := repo.FindNamed(ctx, "by-name-b", bson.M{"name": "b"})
+		if err != nil || len(named) != 1 {
+			t.Fatalf("FindNamed() = %v, %v", named, err)
+		}
+	}
+	if calls[mongo.OpFind] != 1 {
+		t.Fatalf("Find reached repository %d times, want 1", calls[mongo.OpFind])
+	}
+
+	if _, err := repo.DeleteById(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := repo.FindById(ctx, b.ID); nil != found {
+		t.Fatalf("FindById() after DeleteById = %v", found)
+	}
+	named, err := repo.FindNamed(ctx, "by-name-b", bson.M{"name": "b"})
+	if err != nil || len(named) != 0 {
+		t.Fatalf("FindNamed() after DeleteById = %v, %v", named, err)
+	}
+
+	if _, err := repo.Delete(ctx, bson.M{}); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := repo.FindById(ctx, a.ID); nil != found {
+		t.Fatalf("FindById() after Delete = %v", found)
+	}
+
+	if _, err := repo.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{"name": "x"}}); !errors.Is(err, ErrUpdateNotSupported) {
+		t.Fatalf("UpdateMany() error = %v, want ErrUpdateNotSupported", err)
+	}
+}
+
+type tenantUser struct {
+	ID                        mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	mongoxentity.TenantFields `bson:",inline"`
+	Name                      string `bson:"name"`
+}
+
+// tenantScoped 模拟 DocumentRepository 的租户限定：其他租户的实体视为不存在
+func tenantScoped(calls map[string]int) mongo.Interceptor {
+	return func(ctx context.Context, inv *mongo.Invocation, next mongo.Handler) error {
+		calls[inv.Operation]++
+		if err := next(ctx, inv); err != nil {
+			return err
+		}
+		tenantId, _ := tenant.FromContext(ctx)
+		switch result := inv.Result.(type) {
+		case *tenantUser:
+			if nil != result && result.TenantId != tenantId && !tenant.IsSystem(ctx) {
+				inv.Result = (*tenantUser)(nil)
+				return common.ErrNoResult
+			}
+		case []*tenantUser:
+			scoped := make([]*tenantUser, 0, len(result))
+			for _, u := range result {
+				if u.TenantId == tenantId || tenant.IsSystem(ctx) {
+					scoped = append(scoped, u)
+				}
+			}
+			inv.Result = scoped
+		}
+		return nil
+	}
+}
+
+func TestRepositoryTenant(t *testing.T) {
+	calls := map[string]int{}
+	repo := NewRepository[tenantUser](mongo.Intercept[tenantUser](memory.NewRepository[tenantUser](), tenantScoped(calls)), NewLRU(100), time.Minute)
+	ctxA := tenant.WithTenant(context.TODO(), "a")
+	ctxB := tenant.WithTenant(context.TODO(), "b")
+
+	u := &tenantUser{Name: "a"}
+	u.TenantId = "a"
+	if _, err := repo.Save(ctxA, u); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := repo.FindById(ctxA, u.ID); err != nil || found.Name != "a" {
+		t.Fatalf("FindById() tenant a = %v, %v", found, err)
+	}
+
+	// 租户 a 的缓存不能返回给租户 b
+	if found, err := repo.FindById(ctxB, u.ID); !errors.Is(err, common.ErrNoResult) || nil != found {
+		t.Fatalf("FindById() tenant b = %v, %v", found, err)
+	}
+	if all, err := repo.FindAllById(ctxB, u.ID); err != nil || len(all) != 0 {
+		t.Fatalf("FindAllById() tenant b = %v, %v", all, err)
+	}
+	if calls[mongo.OpFindById] != 2 || calls[mongo.OpFindAllById] != 1 {
+		t.Fatalf("calls = %v, cache hit served across tenants", calls)
+	}
+	if _, err := repo.FindById(tenant.WithSystem(context.TODO()), u.ID); err != nil || calls[mongo.OpFindById] != 2 {
+		t.Fatalf("FindById() system = %v, calls = %v", err, calls)
+	}
+
+	if named, err := repo.FindNamed(ctxA, "all", bson.M{}); err != nil || len(named) != 1 {
+		t.Fatalf("FindNamed() tenant a = %v, %v", named, err)
+	}
+	if named, err := repo.FindNamed(ctxB, "all", bson.M{}); err != nil || len(named) != 0 {
+		t.Fatalf("FindNamed() tenant b = %v, %v", named, err)
+	}
+}
+
+func TestRepositoryNamedQueryEviction(t *testing.T) {
+	ctx := context.TODO()
+	c := NewLRU(0)
+	repo := NewRepository[user](memory.NewRepository[user](), c, 0)
+
+	u := &user{Name: "a"}
+	for i := 0; i < 10; i++ {
+		if _, err := repo.FindNamed(ctx, "all", bson.M{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Save(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 写操作删除命名查询，不会残留旧的条目
+	if c.Len() != 0 {
+		t.Fatalf("cache Len() = %d after writes, want 0", c.Len())
+	}
+}
+
+func TestRepositoryIncludeDeleted(t *testing.T) {
+	ctx := context.TODO()
+	calls := map[string]int{}
+	repo := NewRepository[user](mongo.Intercept[user](memory.NewRepository[user](), counting(calls)), NewLRU(100), time.Minute)
+
+	u, err := repo.Save(ctx, &user{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []context.Context{ctx, mongo.IncludeDeleted(ctx), ctx, mongo.IncludeDeleted(ctx)} {
+		if _, err := repo.FindById(c, u.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.FindNamed(c, "all", bson.M{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls[mongo.OpFindById] != 2 || calls[mongo.OpFind] != 2 {
+		t.Fatalf("calls = %v, want separate entries for IncludeDeleted", calls)
+	}
+
+	if _, err := repo.Save(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.FindById(mongo.IncludeDeleted(ctx), u.ID); err != nil || calls[mongo.OpFindById] != 3 {
+		t.Fatalf("IncludeDeleted entry not evicted, calls = %v, err = %v", calls, err)
+	}
+}
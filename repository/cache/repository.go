@@ -0,0 +1,321 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aomi-go/data/common/tenant"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrUpdateNotSupported 被包装的仓储没有实现 Updater
+var ErrUpdateNotSupported = errors.New("cache: repository does not support UpdateMany")
+
+// Updater 支持批量更新的仓储，例如 mongo.DocumentRepository
+type Updater interface {
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error)
+}
+
+// NewRepository 为仓储添加读穿透缓存，ttl <= 0 表示缓存不过期
+func NewRepository[E interface{}](target mongo.Repository[E], c Cache, ttl time.Duration) *Repository[E] {
+	return &Repository[E]{
+		Repository: target,
+		cache:      c,
+		ttl:        ttl,
+	}
+}
+
+// Repository 缓存 FindById、FindAllById 以及 FindNamed 的结果。
+// Save、SaveMany、DeleteById 按 ID 失效，FindOneAndModify 失效返回的实体，
+// Delete、UpdateMany 无法确定受影响的 ID，会清空整个缓存；任意写操作都会删除全部命名查询。
+// 返回的实体为缓存值的浅拷贝。
+// 多租户实体只返回给同一租户或系统 context，命名查询按 context 中的租户分别缓存；
+// mongo.IncludeDeleted 的 context 与普通 context 使用不同的缓存。
+type Repository[Entity interface{}] struct {
+	mongo.Repository[Entity]
+	cache Cache
+	ttl   time.Duration
+	// generation 每次写操作递增，用于丢弃写操作期间加载的旧数据
+	generation atomic.Uint64
+
+	mu sync.Mutex
+	// queries 已缓存的命名查询键，写操作时逐个删除
+	queries map[string]struct{}
+}
+
+func (r *Repository[Entity]) FindById(ctx context.Context, id interface{}) (*Entity, error) {
+	key, ok := idKey(id)
+	if !ok {
+		return r.Repository.FindById(ctx, id)
+	}
+	key = withDeleted(ctx, key)
+	if v, ok := r.get(ctx, key); ok {
+		return clone(v), nil
+	}
+
+	generation := r.generation.Load()
+	entity, err := r.Repository.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.set(generation, key, entity)
+	return entity, nil
+}
+
+func (r *Repository[Entity]) FindAllById(ctx context.Context, ids ...interface{}) ([]*Entity, error) {
+	result := make([]*Entity, 0, len(ids))
+	var missing []interface{}
+	for _, id := range ids {
+		key, ok := idKey(id)
+		if !ok {
+			continue
+		}
+		key = withDeleted(ctx, key)
+		if v, ok := r.get(ctx, key); ok {
+			result = append(result, clone(v))
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	generation := r.generation.Load()
+	loaded, err := r.Repository.FindAllById(ctx, missing...)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range loaded {
+		if key, ok := entityKey(entity); ok {
+			r.set(generation, withDeleted(ctx, key), entity)
+		}
+	}
+	return append(result, loaded...), nil
+}
+
+func (r *Repository[Entity]) ExistsById(ctx context.Context, id interface{}) (bool, error) {
+	if key, ok := idKey(id); ok {
+		if _, ok := r.get(ctx, withDeleted(ctx, key)); ok {
+			return true, nil
+		}
+	}
+	return r.Repository.ExistsById(ctx, id)
+}
+
+// FindNamed 以 name 为键缓存 Find 的结果，name 需要唯一标识查询条件与选项
+func (r *Repository[Entity]) FindNamed(ctx context.Context, name string, filter interface{}, opts ...*options.FindOptions) ([]*Entity, error) {
+	key := withDeleted(ctx, queryKey(scopeOf(ctx), name))
+	if v, ok := r.cache.Get(key); ok {
+		return cloneAll(v.([]*Entity)), nil
+	}
+
+	generation := r.generation.Load()
+	entities, err := r.Repository.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation.Load() == generation {
+		if nil == r.queries {
+			r.queries = map[string]struct{}{}
+		}
+		r.queries[key] = struct{}{}
+		r.cache.Set(key, cloneAll(entities), r.ttl)
+	}
+	return entities, nil
+}
+
+func (r *Repository[Entity]) Save(ctx context.Context, entity *Entity) (*Entity, error) {
+	r.generation.Add(1)
+	saved, err := r.Repository.Save(ctx, entity)
+	r.evictEntities(entity)
+	return saved, err
+}
+
+func (r *Repository[Entity]) SaveMany(ctx context.Context, entities []*Entity) ([]*Entity, error) {
+	r.generation.Add(1)
+	saved, err := r.Repository.SaveMany(ctx, entities)
+	r.evictEntities(entities...)
+	return saved, err
+}
+
+func (r *Repository[Entity]) DeleteById(ctx context.Context, id interface{}) (bool, error) {
+	r.generation.Add(1)
+	deleted, err := r.Repository.DeleteById(ctx, id)
+	r.Evict(id)
+	return deleted, err
+}
+
+func (r *Repository[Entity]) Delete(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	r.generation.Add(1)
+	n, err := r.Repository.Delete(ctx, filter, opts...)
+	r.clear()
+	return n, err
+}
+
+func (r *Repository[Entity]) FindOneAndModify(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (*Entity, error) {
+	r.generation.Add(1)
+	entity, err := r.Repository.FindOneAndModify(ctx, filter, update, opts...)
+	if nil != entity {
+		r.evictEntities(entity)
+	} else if err != nil {
+		// 无法确定受影响的文档
+		r.clear()
+	}
+	return entity, err
+}
+
+// UpdateMany 调用被包装仓储的 UpdateMany 并清空缓存
+func (r *Repository[Entity]) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error) {
+	updater, ok := r.Repository.(Updater)
+	if !ok {
+		return 0, ErrUpdateNotSupported
+	}
+	r.generation.Add(1)
+	n, err := updater.UpdateMany(ctx, filter, update, opts...)
+	r.clear()
+	return n, err
+}
+
+// Evict 失效指定 ID 的缓存以及全部命名查询
+func (r *Repository[Entity]) Evict(ids ...interface{}) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if key, ok := idKey(id); ok {
+			keys = append(keys, key)
+		}
+	}
+	r.evict(keys)
+}
+
+// EvictAll 清空缓存
+func (r *Repository[Entity]) EvictAll() {
+	r.clear()
+}
+
+// evict 删除实体键（包括 IncludeDeleted 的键）以及全部命名查询
+func (r *Repository[Entity]) evict(keys []string) {
+	r.generation.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := make([]string, 0, len(keys)*2+len(r.queries))
+	for _, key := range keys {
+		all = append(all, key, deletedPrefix+key)
+	}
+	for key := range r.queries {
+		all = append(all, key)
+	}
+	r.queries = nil
+	r.cache.Delete(all...)
+}
+
+func (r *Repository[Entity]) clear() {
+	r.generation.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = nil
+	r.cache.Clear()
+}
+
+// get 读取对 ctx 可见的缓存实体
+func (r *Repository[Entity]) get(ctx context.Context, key string) (*Entity, bool) {
+	v, ok := r.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entity := v.(*Entity)
+	return entity, visible(ctx, entity)
+}
+
+func (r *Repository[Entity]) set(generation uint64, key string, entity *Entity) {
+	// 加载期间发生过写操作时不写入，避免缓存旧数据；检查与写入在同一把锁内，不会与 evict 交错
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation.Load() != generation {
+		return
+	}
+	r.cache.Set(key, clone(entity), r.ttl)
+}
+
+func (r *Repository[Entity]) evictEntities(entities ...*Entity) {
+	keys := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		if key, ok := entityKey(entity); ok {
+			keys = append(keys, key)
+		}
+	}
+	r.evict(keys)
+}
+
+func idKey(id interface{}) (string, bool) {
+	oid, ok := mongo.ToObjectIdWithCheck(id)
+	if !ok || oid.IsZero() {
+		return "", false
+	}
+	return "id:" + oid.Hex(), true
+}
+
+func entityKey(entity interface{}) (string, bool) {
+	field, ok := mongo.GetIdFieldValue(entity, "ID")
+	if !ok {
+		return "", false
+	}
+	return idKey(field.Interface())
+}
+
+func queryKey(scope string, name string) string {
+	return "q:" + scope + ":" + name
+}
+
+const deletedPrefix = "deleted:"
+
+// withDeleted IncludeDeleted 的 context 使用单独的键，避免与排除已删除数据的查询共用缓存
+func withDeleted(ctx context.Context, key string) string {
+	if mongo.IsIncludeDeleted(ctx) {
+		return deletedPrefix + key
+	}
+	return key
+}
+
+// scopeOf 命名查询的租户范围：系统 context 为 *，没有租户时为空
+func scopeOf(ctx context.Context) string {
+	if tenant.IsSystem(ctx) {
+		return "*"
+	}
+	tenantId, _ := tenant.FromContext(ctx)
+	return tenantId
+}
+
+// visible 缓存的多租户实体只对同一租户或系统 context 可见，
+// 其他情况交给被包装的仓储按租户限定查询
+func visible(ctx context.Context, entity interface{}) bool {
+	t, ok := entity.(mongoxentity.TenantAware)
+	if !ok || tenant.IsSystem(ctx) {
+		return true
+	}
+	tenantId, ok := tenant.FromContext(ctx)
+	return ok && tenantId == t.GetTenantId()
+}
+
+func clone[T interface{}](v *T) *T {
+	if nil == v {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func cloneAll[T interface{}](vs []*T) []*T {
+	result := make([]*T, len(vs))
+	for i, v := range vs {
+		result[i] = clone(v)
+	}
+	return result
+}
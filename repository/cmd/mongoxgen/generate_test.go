@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate("testdata/entity", []string{"User"}, "mongox_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := strings.Join(strings.Fields(string(src)), " ")
+	for _, want := range []string{
+		"// Code generated by mongoxgen. DO NOT EDIT.",
+		`UserFieldId = "_id"`,
+		`UserFieldCreatedAt = "created_at"`,
+		`UserFieldName = "name"`,
+		`UserFieldAddress = "address"`,
+		`UserFieldAddressCity = "address.city"`,
+		`UserFieldAddressStreet = "address.street"`,
+		`UserFieldExtra = "extra"`,
+		"func UserQuery() *UserQueryBuilder",
+		"func (q *UserQueryBuilder) NameLike(value string) *UserQueryBuilder",
+		"func (q *UserQueryBuilder) AgeBetween(min int, max int) *UserQueryBuilder",
+		"func (q *UserQueryBuilder) StatusIn(values ...Status) *UserQueryBuilder",
+		"func (q *UserQueryBuilder) TagsContains(value string) *UserQueryBuilder",
+		"func (q *UserQueryBuilder) BirthdayGte(value time.Time) *UserQueryBuilder",
+		"func (q *UserQueryBuilder) IdIn(values ...mongoxentity.StrObjectId) *UserQueryBuilder",
+		`"github.com/aomi-go/data/mongo/mongoxentity"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Password", "secret", "ExtraIs", "TagsGt", "AuditFields"} {
+		if strings.Contains(code, unwanted) {
+			t.Errorf("generated code contains %q", unwanted)
+		}
+	}
+
+	if _, err := Generate("testdata/entity", []string{"Missing"}, "mongox_gen.go"); err == nil {
+		t.Error("Generate() with unknown type, want error")
+	}
+}
+
+// TestGenerateCompiles 生成的代码与实体包一起通过类型检查
+func TestGenerateCompiles(t *testing.T) {
+	src, err := Generate("testdata/entity", []string{"User", "Address"}, "mongox_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	entity, err := os.ReadFile("testdata/entity/user.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), entity, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "mongox_gen.go"), src, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 生成文件被跳过，改用其它输出名时生成文件参与类型检查
+	if _, err := Generate(dir, []string{"User"}, "other_gen.go"); err != nil {
+		t.Fatalf("generated code does not type check: %v", err)
+	}
+}
+
+func TestGenerateMultipleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	entity, err := os.ReadFile("testdata/entity/user.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "user.go"), entity, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 同一个包中的多个生成文件不能重复声明辅助函数
+	for typeName, output := range map[string]string{"User": "user_gen.go", "Address": "address_gen.go"} {
+		src, err := Generate("testdata/entity", []string{typeName}, output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, output), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Generate(dir, []string{"User"}, "other_gen.go"); err != nil {
+		t.Fatalf("generated files do not type check together: %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldKind 决定字段生成哪些查询方法
+type fieldKind int
+
+const (
+	// kindScalar 可比较的值：Is、Ne、In、NotIn
+	kindScalar fieldKind = iota
+	// kindOrdered 有序的值：另外生成 Gt、Gte、Lt、Lte、Between
+	kindOrdered
+	// kindString 字符串：另外生成 Like、LeftLike、RightLike
+	kindString
+	// kindSlice 数组：Contains、ContainsAny
+	kindSlice
+	// kindOther map、interface 等：只生成 Exists
+	kindOther
+)
+
+// field 实体中的一个 bson 字段路径
+type field struct {
+	// path bson 路径，如 address.city
+	path string
+	// name Go 标识符，如 AddressCity
+	name string
+	kind fieldKind
+	// typ 查询参数的类型，数组为元素类型
+	typ string
+}
+
+// Generate 解析 dir 下的包并为 typeNames 生成代码，output 为输出文件名（解析时跳过）
+func Generate(dir string, typeNames []string, output string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		name := fi.Name()
+		return !strings.HasSuffix(name, "_test.go") && name != filepath.Base(output)
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+	var files []*ast.File
+	var pkgName string
+	for name, p := range pkgs {
+		pkgName = name
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return fset.Position(files[i].Pos()).Filename < fset.Position(files[j].Pos()).Filename
+	})
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check(abs, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, imports: map[string]string{}}
+	for _, typeName := range typeNames {
+		typeName = strings.TrimSpace(typeName)
+		obj := pkg.Scope().Lookup(typeName)
+		if nil == obj {
+			return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a named type", typeName)
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("%s is not a struct", typeName)
+		}
+		var fields []field
+		g.collect(st, "", "", map[*types.Named]bool{named: true}, &fields)
+		g.entities = append(g.entities, entity{name: typeName, fields: fields})
+	}
+	return g.render(pkgName)
+}
+
+type entity struct {
+	name   string
+	fields []field
+}
+
+type generator struct {
+	pkg      *types.Package
+	imports  map[string]string
+	entities []entity
+}
+
+// collect 递归收集字段，inline 结构体平铺，嵌套结构体展开为点号路径
+func (g *generator) collect(st *types.Struct, path string, name string, visiting map[*types.Named]bool, fields *[]field) {
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if !v.Exported() {
+			continue
+		}
+		key, inline, skip := parseBsonTag(v.Name(), st.Tag(i))
+		if skip {
+			continue
+		}
+
+		t := v.Type()
+		if p, ok := t.(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if nested, named, ok := nestedStruct(t); ok && !visiting[named] {
+			childPath, childName := path, name
+			if !inline {
+				childPath, childName = join(path, key), name+v.Name()
+				*fields = append(*fields, field{path: childPath, name: childName, kind: kindOther})
+			}
+			if nil != named {
+				visiting[named] = true
+			}
+			g.collect(nested, childPath, childName, visiting, fields)
+			if nil != named {
+				delete(visiting, named)
+			}
+			continue
+		}
+		if inline {
+			continue
+		}
+
+		f := field{path: join(path, key), name: name + v.Name(), kind: kindOf(t)}
+		switch f.kind {
+		case kindSlice:
+			f.typ = g.typeString(t.Underlying().(*types.Slice).Elem())
+		case kindOther:
+		default:
+			f.typ = g.typeString(t)
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+// nestedStruct 需要展开的结构体；time.Time、decimal.Decimal 等没有导出字段的结构体作为整体值
+func nestedStruct(t types.Type) (*types.Struct, *types.Named, bool) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil, false
+	}
+	exported := false
+	for i := 0; i < st.NumFields(); i++ {
+		exported = exported || st.Field(i).Exported()
+	}
+	named, _ := t.(*types.Named)
+	return st, named, exported
+}
+
+func kindOf(t types.Type) fieldKind {
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if nil != obj.Pkg() && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return kindOrdered
+		}
+		if strings.HasSuffix(strings.ToLower(obj.Name()), "objectid") {
+			return kindOrdered
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return kindString
+		case u.Info()&types.IsNumeric != 0:
+			return kindOrdered
+		}
+		return kindScalar
+	case *types.Slice:
+		if b, ok := u.Elem().Underlying().(*types.Basic); ok && b.Kind() == types.Byte {
+			return kindScalar
+		}
+		return kindSlice
+	case *types.Array:
+		return kindScalar
+	case *types.Struct:
+		// 没有导出字段的结构体，例如 decimal.Decimal
+		return kindOrdered
+	}
+	return kindOther
+}
+
+func (g *generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(p *types.Package) string {
+		if p == g.pkg {
+			return ""
+		}
+		g.imports[p.Path()] = p.Name()
+		return p.Name()
+	})
+}
+
+// parseBsonTag 与 bsoncodec.DefaultStructTagParser 的规则一致：默认键名为小写的字段名
+func parseBsonTag(fieldName string, tag string) (key string, inline bool, skip bool) {
+	key = strings.ToLower(fieldName)
+	bsonTag, ok := reflect.StructTag(tag).Lookup("bson")
+	if !ok && !strings.Contains(tag, ":") && "" != tag {
+		bsonTag = tag
+	}
+	if "-" == bsonTag {
+		return "", false, true
+	}
+	for i, part := range strings.Split(bsonTag, ",") {
+		if i == 0 {
+			if "" != part {
+				key = part
+			}
+			continue
+		}
+		if "inline" == part {
+			inline = true
+		}
+	}
+	return key, inline, false
+}
+
+func join(prefix string, key string) string {
+	if "" == prefix {
+		return key
+	}
+	return prefix + "." + key
+}
+
+const mongoxImport = "github.com/aomi-go/data/repository/mongo"
+
+func (g *generator) render(pkgName string) ([]byte, error) {
+	var body bytes.Buffer
+	for _, e := range g.entities {
+		g.renderEntity(&body, e)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by mongoxgen. DO NOT EDIT.\n\npackage %s\n\n", pkgName)
+	// 标准库与第三方包分组
+	var std, others []string
+	for path := range g.imports {
+		if strings.Contains(strings.Split(path, "/")[0], ".") {
+			others = append(others, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	others = append(others, mongoxImport)
+	sort.Strings(others)
+	out.WriteString("import (\n")
+	for _, path := range std {
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	if len(std) > 0 {
+		out.WriteString("\n")
+	}
+	for _, path := range others {
+		if path == mongoxImport {
+			fmt.Fprintf(&out, "\tmongox %q\n", path)
+			continue
+		}
+		fmt.Fprintf(&out, "\t%q\n", path)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, out.String())
+	}
+	return src, nil
+}
+
+func (g *generator) renderEntity(w *bytes.Buffer, e entity) {
+	q := e.name + "QueryBuilder"
+
+	fmt.Fprintf(w, "\n// %s 的 bson 字段路径\nconst (\n", e.name)
+	for _, f := range e.fields {
+		fmt.Fprintf(w, "\t%sField%s = %q\n", e.name, f.name, f.path)
+	}
+	w.WriteString(")\n")
+
+	fmt.Fprintf(w, `
+// %[1]s %[2]s 的类型化查询构建器
+type %[1]s struct {
+	*mongox.QueryBuilder
+}
+
+// %[2]sQuery 创建 %[2]s 的查询构建器
+func %[2]sQuery() *%[1]s {
+	return &%[1]s{QueryBuilder: mongox.NewQueryBuilder()}
+}
+`, q, e.name)
+
+	for _, f := range e.fields {
+		c := e.name + "Field" + f.name
+		method := func(suffix string, params string, call string) {
+			fmt.Fprintf(w, "\nfunc (q *%s) %s%s(%s) *%s {\n\tq.QueryBuilder.%s\n\treturn q\n}\n", q, f.name, suffix, params, q, call)
+		}
+		method("Exists", "exists bool", fmt.Sprintf("Exists(%s, exists)", c))
+		switch f.kind {
+		case kindOther:
+			continue
+		case kindSlice:
+			method("Contains", "value "+f.typ, fmt.Sprintf("Is(%s, value)", c))
+			method("ContainsAny", "values ..."+f.typ, fmt.Sprintf("In(%s, mongox.Values(values)...)", c))
+			continue
+		}
+		method("Is", "value "+f.typ, fmt.Sprintf("Is(%s, value)", c))
+		method("Ne", "value "+f.typ, fmt.Sprintf("Ne(%s, value)", c))
+		method("In", "values ..."+f.typ, fmt.Sprintf("In(%s, mongox.Values(values)...)", c))
+		method("NotIn", "values ..."+f.typ, fmt.Sprintf("NotIn(%s, mongox.Values(values)...)", c))
+		if f.kind == kindScalar {
+			continue
+		}
+		method("Gt", "value "+f.typ, fmt.Sprintf("Gt(%s, value)", c))
+		method("Gte", "value "+f.typ, fmt.Sprintf("Gte(%s, value)", c))
+		method("Lt", "value "+f.typ, fmt.Sprintf("Lt(%s, value)", c))
+		method("Lte", "value "+f.typ, fmt.Sprintf("Lte(%s, value)", c))
+		method("Between", "min "+f.typ+", max "+f.typ, fmt.Sprintf("Between(%s, min, max)", c))
+		if f.kind == kindString {
+			method("Like", "value string", fmt.Sprintf("Like(%s, value)", c))
+			method("LeftLike", "value string", fmt.Sprintf("LeftLike(%s, value)", c))
+			method("RightLike", "value string", fmt.Sprintf("RightLike(%s, value)", c))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// mongoxgen 根据实体的 bson 标签生成字段路径常量与类型化的查询构建器。
+//
+// 在实体所在的文件中添加：
+//
+//	//go:generate go run github.com/aomi-go/data/repository/cmd/mongoxgen -type User,Order
+//
+// 将生成 mongox_gen.go，包含 UserFieldName = "name"、UserFieldAddressCity = "address.city" 等常量，
+// 以及 UserQuery().NameLike("x").AgeGt(3) 形式的查询构建器
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "逗号分隔的实体类型名称，必填")
+	dir := flag.String("dir", ".", "实体所在的包目录")
+	output := flag.String("output", "mongox_gen.go", "输出文件名，相对于 -dir")
+	flag.Parse()
+
+	if "" == *typeNames {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := Generate(*dir, strings.Split(*typeNames, ","), *output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mongoxgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "mongoxgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+)
+
+type Status string
+
+type Address struct {
+	City   string `bson:"city"`
+	Street string
+}
+
+type User struct {
+	Id                       mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	mongoxentity.AuditFields `bson:",inline"`
+	Name                     string            `bson:"name"`
+	Age                      int               `bson:"age"`
+	Status                   Status            `bson:"status"`
+	Tags                     []string          `bson:"tags"`
+	Address                  *Address          `bson:"address"`
+	Extra                    map[string]string `bson:"extra"`
+	Birthday                 time.Time         `bson:"birthday"`
+	Password                 string            `bson:"-"`
+	secret                   string
+}
@@ -0,0 +1,145 @@
+// Package criteria 以与存储无关的方式描述查询条件，可编译为 MongoDB 过滤条件、SQL WHERE 子句，
+// 也可以直接在内存中求值，便于同一套查询逻辑在不同存储或测试替身之间切换。
+package criteria
+
+// Operator 条件操作符
+type Operator string
+
+const (
+	OpEq         = Operator("eq")
+	OpNe         = Operator("ne")
+	OpGt         = Operator("gt")
+	OpGte        = Operator("gte")
+	OpLt         = Operator("lt")
+	OpLte        = Operator("lte")
+	OpIn         = Operator("in")
+	OpNotIn      = Operator("nin")
+	OpLike       = Operator("like")
+	OpStartsWith = Operator("startsWith")
+	OpEndsWith   = Operator("endsWith")
+	OpIsNull     = Operator("isNull")
+	OpNotNull    = Operator("notNull")
+	OpAnd        = Operator("and")
+	OpOr         = Operator("or")
+	OpNot        = Operator("not")
+)
+
+// Criteria 查询条件树，叶子节点为字段谓词，分支节点为 and/or/not
+type Criteria struct {
+	Op       Operator
+	Field    string
+	Value    interface{}
+	Values   []interface{}
+	Children []*Criteria
+}
+
+// Eq 构建等于条件，value 为 nil 时等价于 IsNull
+func Eq(field string, value interface{}) *Criteria {
+	if nil == value {
+		return IsNull(field)
+	}
+	return &Criteria{Op: OpEq, Field: field, Value: value}
+}
+
+// Ne 构建不等于条件，字段不存在或为空时同样满足
+func Ne(field string, value interface{}) *Criteria {
+	if nil == value {
+		return NotNull(field)
+	}
+	return &Criteria{Op: OpNe, Field: field, Value: value}
+}
+
+// Gt 构建大于条件
+func Gt(field string, value interface{}) *Criteria {
+	return &Criteria{Op: OpGt, Field: field, Value: value}
+}
+
+// Gte 构建大于等于条件
+func Gte(field string, value interface{}) *Criteria {
+	return &Criteria{Op: OpGte, Field: field, Value: value}
+}
+
+// Lt 构建小于条件
+func Lt(field string, value interface{}) *Criteria {
+	return &Criteria{Op: OpLt, Field: field, Value: value}
+}
+
+// Lte 构建小于等于条件
+func Lte(field string, value interface{}) *Criteria {
+	return &Criteria{Op: OpLte, Field: field, Value: value}
+}
+
+// Between 构建闭区间条件
+func Between(field string, min interface{}, max interface{}) *Criteria {
+	return And(Gte(field, min), Lte(field, max))
+}
+
+// In 构建包含条件，values 为空时不匹配任何数据
+func In(field string, values ...interface{}) *Criteria {
+	return &Criteria{Op: OpIn, Field: field, Values: values}
+}
+
+// NotIn 构建不包含条件，字段不存在或为空时同样满足
+func NotIn(field string, values ...interface{}) *Criteria {
+	return &Criteria{Op: OpNotIn, Field: field, Values: values}
+}
+
+// Like 构建忽略大小写的包含匹配，value 按字面量处理
+func Like(field string, value string) *Criteria {
+	return &Criteria{Op: OpLike, Field: field, Value: value}
+}
+
+// StartsWith 构建忽略大小写的前缀匹配
+func StartsWith(field string, value string) *Criteria {
+	return &Criteria{Op: OpStartsWith, Field: field, Value: value}
+}
+
+// EndsWith 构建忽略大小写的后缀匹配
+func EndsWith(field string, value string) *Criteria {
+	return &Criteria{Op: OpEndsWith, Field: field, Value: value}
+}
+
+// IsNull 字段不存在或为空
+func IsNull(field string) *Criteria {
+	return &Criteria{Op: OpIsNull, Field: field}
+}
+
+// NotNull 字段存在且不为空
+func NotNull(field string) *Criteria {
+	return &Criteria{Op: OpNotNull, Field: field}
+}
+
+// And 所有条件都满足，没有条件时匹配全部数据
+func And(children ...*Criteria) *Criteria {
+	return &Criteria{Op: OpAnd, Children: compact(children)}
+}
+
+// Or 任一条件满足，没有条件时不匹配任何数据
+func Or(children ...*Criteria) *Criteria {
+	return &Criteria{Op: OpOr, Children: compact(children)}
+}
+
+// Not 条件取反
+func Not(child *Criteria) *Criteria {
+	return &Criteria{Op: OpNot, Children: compact([]*Criteria{child})}
+}
+
+// And 追加条件，便于链式构建
+func (c *Criteria) And(children ...*Criteria) *Criteria {
+	return And(append([]*Criteria{c}, children...)...)
+}
+
+// Or 追加条件，便于链式构建
+func (c *Criteria) Or(children ...*Criteria) *Criteria {
+	return Or(append([]*Criteria{c}, children...)...)
+}
+
+func compact(children []*Criteria) []*Criteria {
+	result := make([]*Criteria, 0, len(children))
+	for _, child := range children {
+		if nil != child {
+			result = append(result, child)
+		}
+	}
+	return result
+}
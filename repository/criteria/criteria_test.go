@@ -0,0 +1,135 @@
+package criteria
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+type item struct {
+	Name  string   `bson:"name"`
+	Price int      `bson:"price"`
+	Tags  []string `bson:"tags"`
+	Note  *string  `bson:"note,omitempty"`
+}
+
+func TestToSQL(t *testing.T) {
+	c := And(
+		Eq("name", "a"),
+		Or(Between("price", 1, 10), In("tags")),
+		Not(Like("note", "50%_off")),
+	)
+	clause, args, err := c.ToSQL(func(s string) string { return `"` + s + `"` })
+	if err != nil {
+		t.Fatalf("ToSQL() error = %v", err)
+	}
+	want := `("name" = ? AND (("price" >= ? AND "price" <= ?) OR 1 = 0) AND (LOWER("note") LIKE ? ESCAPE '\') IS NOT TRUE)`
+	if clause != want {
+		t.Errorf("ToSQL() clause = %s\nwant %s", clause, want)
+	}
+	wantArgs := []interface{}{"a", 1, 10, `%50\%\_off%`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("ToSQL() args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	doc := item{Name: "Apple Pie", Price: 5, Tags: []string{"food"}}
+
+	tests := []struct {
+		name string
+		c    *Criteria
+		want bool
+	}{
+		{"eq", Eq("name", "Apple Pie"), true},
+		{"ne missing", Ne("note", "x"), true},
+		{"range", Between("price", 1, 5), true},
+		{"gt", Gt("price", 5), false},
+		{"in array", In("tags", "drink", "food"), true},
+		{"empty in", In("tags"), false},
+		{"not in", NotIn("name", "x"), true},
+		{"like", Like("name", "pie"), true},
+		{"like literal", Like("name", "a.ple"), false},
+		{"starts with", StartsWith("name", "apple"), true},
+		{"ends with", EndsWith("name", "apple"), false},
+		{"is null", IsNull("note"), true},
+		{"not null", NotNull("name"), true},
+		{"or", Or(Eq("name", "x"), Lt("price", 10)), true},
+		{"empty or", Or(), false},
+		{"empty and", And(), true},
+		{"not", Not(Eq("name", "Apple Pie")), false},
+		{"chain", Eq("name", "Apple Pie").And(Gte("price", 6)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.c.Match(doc)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSQLMatchesMongo 同一条件在 SQL 与 Match（MongoDB 语义）下返回相同的数据
+func TestSQLMatchesMongo(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE item (name TEXT NOT NULL, price INTEGER NOT NULL, note TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	sale, other := "50% OFF today", "fresh"
+	items := []item{{Name: "a", Price: 1, Note: &sale}, {Name: "b", Price: 2, Note: &other}, {Name: "c", Price: 3}}
+	for _, it := range items {
+		if _, err := db.Exec(`INSERT INTO item (name, price, note) VALUES (?, ?, ?)`, it.Name, it.Price, it.Note); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []*Criteria{
+		Not(Like("note", "50% off")),
+		Not(Eq("note", "fresh")),
+		Not(Ne("note", "fresh")),
+		Not(Or(In("note", "fresh"), Gt("price", 2))),
+		Not(IsNull("note")),
+		Not(Not(Like("note", "off"))),
+		And(Lt("price", 3), Not(StartsWith("note", "fresh"))),
+	} {
+		clause, args, err := c.ToSQL(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := db.Query("SELECT name FROM item WHERE "+clause+" ORDER BY name", args...)
+		if err != nil {
+			t.Fatalf("query %s: %v", clause, err)
+		}
+		var got []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, name)
+		}
+		rows.Close()
+
+		var want []string
+		for _, it := range items {
+			if ok, err := c.Match(it); err != nil {
+				t.Fatal(err)
+			} else if ok {
+				want = append(want, it.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: SQL rows = %v, Match rows = %v", clause, got, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package criteria
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ToMongo 编译为 MongoDB 过滤条件，可直接传给 DocumentRepository.Find 或 mongo.QueryBuilderOf
+func (c *Criteria) ToMongo() (bson.M, error) {
+	if nil == c {
+		return bson.M{}, nil
+	}
+	switch c.Op {
+	case OpEq:
+		return bson.M{c.Field: bson.M{"$eq": c.Value}}, nil
+	case OpNe, OpGt, OpGte, OpLt, OpLte:
+		return bson.M{c.Field: bson.M{"$" + string(c.Op): c.Value}}, nil
+	case OpIn, OpNotIn:
+		values := c.Values
+		if nil == values {
+			values = []interface{}{}
+		}
+		return bson.M{c.Field: bson.M{"$" + string(c.Op): values}}, nil
+	case OpLike, OpStartsWith, OpEndsWith:
+		s, ok := c.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("criteria: %s on %s requires a string", c.Op, c.Field)
+		}
+		pattern := regexp.QuoteMeta(s)
+		switch c.Op {
+		case OpStartsWith:
+			pattern = "^" + pattern
+		case OpEndsWith:
+			pattern = pattern + "$"
+		}
+		return bson.M{c.Field: bson.M{"$regex": primitive.Regex{Pattern: pattern, Options: "i"}}}, nil
+	case OpIsNull:
+		return bson.M{c.Field: nil}, nil
+	case OpNotNull:
+		return bson.M{c.Field: bson.M{"$ne": nil}}, nil
+	case OpAnd, OpOr:
+		if len(c.Children) == 1 {
+			return c.Children[0].ToMongo()
+		}
+		if len(c.Children) == 0 {
+			if c.Op == OpAnd {
+				return bson.M{}, nil
+			}
+			// 空的 $or 不合法，使用 $nor: [{}] 表示不匹配任何数据
+			return bson.M{"$nor": bson.A{bson.M{}}}, nil
+		}
+		children, err := toMongoAll(c.Children)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$" + string(c.Op): children}, nil
+	case OpNot:
+		children, err := toMongoAll(c.Children)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) == 0 {
+			return bson.M{"$nor": bson.A{bson.M{}}}, nil
+		}
+		return bson.M{"$nor": children}, nil
+	}
+	return nil, fmt.Errorf("criteria: unsupported operator %s", c.Op)
+}
+
+func toMongoAll(children []*Criteria) (bson.A, error) {
+	result := make(bson.A, 0, len(children))
+	for _, child := range children {
+		f, err := child.ToMongo()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// Match 在内存中对文档求值，语义与 MongoDB 服务端一致（见 matcher 包）
+func (c *Criteria) Match(doc interface{}) (bool, error) {
+	filter, err := c.ToMongo()
+	if err != nil {
+		return false, err
+	}
+	return matcher.Match(filter, doc)
+}
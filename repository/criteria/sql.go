@@ -0,0 +1,110 @@
+package criteria
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToSQL 编译为 SQL WHERE 子句，使用 ? 作为占位符，quote 用于引用列名（为 nil 时不引用）。
+// 为与 MongoDB 语义保持一致，ne、nin 同样匹配 NULL；like 系列忽略大小写并按字面量匹配；
+// not 编译为 IS NOT TRUE，被取反的条件因 NULL 结果未知时同样匹配，与 $nor 一致。
+func (c *Criteria) ToSQL(quote func(string) string) (string, []interface{}, error) {
+	if nil == quote {
+		quote = func(s string) string { return s }
+	}
+	if nil == c {
+		return "1 = 1", nil, nil
+	}
+
+	column := ""
+	if "" != c.Field {
+		parts := strings.Split(c.Field, ".")
+		for i, p := range parts {
+			parts[i] = quote(p)
+		}
+		column = strings.Join(parts, ".")
+	}
+
+	switch c.Op {
+	case OpEq:
+		return column + " = ?", []interface{}{c.Value}, nil
+	case OpNe:
+		return fmt.Sprintf("(%s <> ? OR %s IS NULL)", column, column), []interface{}{c.Value}, nil
+	case OpGt:
+		return column + " > ?", []interface{}{c.Value}, nil
+	case OpGte:
+		return column + " >= ?", []interface{}{c.Value}, nil
+	case OpLt:
+		return column + " < ?", []interface{}{c.Value}, nil
+	case OpLte:
+		return column + " <= ?", []interface{}{c.Value}, nil
+	case OpIn, OpNotIn:
+		if len(c.Values) == 0 {
+			if c.Op == OpIn {
+				return "1 = 0", nil, nil
+			}
+			return "1 = 1", nil, nil
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(c.Values)), ", ")
+		if c.Op == OpIn {
+			return fmt.Sprintf("%s IN (%s)", column, placeholders), c.Values, nil
+		}
+		return fmt.Sprintf("(%s NOT IN (%s) OR %s IS NULL)", column, placeholders, column), c.Values, nil
+	case OpLike, OpStartsWith, OpEndsWith:
+		s, ok := c.Value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("criteria: %s on %s requires a string", c.Op, c.Field)
+		}
+		pattern := escapeLike(strings.ToLower(s))
+		switch c.Op {
+		case OpLike:
+			pattern = "%" + pattern + "%"
+		case OpStartsWith:
+			pattern = pattern + "%"
+		case OpEndsWith:
+			pattern = "%" + pattern
+		}
+		return fmt.Sprintf("LOWER(%s) LIKE ? ESCAPE '\\'", column), []interface{}{pattern}, nil
+	case OpIsNull:
+		return column + " IS NULL", nil, nil
+	case OpNotNull:
+		return column + " IS NOT NULL", nil, nil
+	case OpAnd, OpOr:
+		if len(c.Children) == 0 {
+			if c.Op == OpAnd {
+				return "1 = 1", nil, nil
+			}
+			return "1 = 0", nil, nil
+		}
+		var clauses []string
+		var args []interface{}
+		for _, child := range c.Children {
+			clause, childArgs, err := child.ToSQL(quote)
+			if err != nil {
+				return "", nil, err
+			}
+			clauses = append(clauses, clause)
+			args = append(args, childArgs...)
+		}
+		if len(clauses) == 1 {
+			return clauses[0], args, nil
+		}
+		sep := " AND "
+		if c.Op == OpOr {
+			sep = " OR "
+		}
+		return "(" + strings.Join(clauses, sep) + ")", args, nil
+	case OpNot:
+		clause, args, err := Or(c.Children...).ToSQL(quote)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + clause + ") IS NOT TRUE", args, nil
+	}
+	return "", nil, fmt.Errorf("criteria: unsupported operator %s", c.Op)
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
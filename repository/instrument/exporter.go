@@ -0,0 +1,156 @@
+package instrument
+
+import (
+	"sort"
+	"sync"
+)
+
+// DefaultBuckets 默认的耗时直方图桶上限，单位秒
+var DefaultBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Key 指标的维度
+type Key struct {
+	Collection string
+	Operation  string
+}
+
+// Stats 某个集合上某种操作的累计指标
+type Stats struct {
+	Count     int64
+	Errors    int64
+	Documents int64
+	Slow      int64
+	// Sum 总耗时，单位秒
+	Sum float64
+	// Buckets 与桶上限一一对应的累计计数（耗时 <= 上限的操作数）
+	Buckets []int64
+}
+
+// aggregator 按 Key 汇总操作记录
+type aggregator struct {
+	mu      sync.Mutex
+	buckets []float64
+	stats   map[Key]*Stats
+}
+
+func newAggregator(buckets []float64) aggregator {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return aggregator{buckets: buckets, stats: map[Key]*Stats{}}
+}
+
+func (a *aggregator) observe(e Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.statsOf(e)
+	seconds := e.Duration.Seconds()
+	s.Count++
+	s.Documents += e.Documents
+	s.Sum += seconds
+	if nil != e.Err {
+		s.Errors++
+	}
+	for i, upper := range a.buckets {
+		if seconds <= upper {
+			s.Buckets[i]++
+		}
+	}
+}
+
+func (a *aggregator) slow(e Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.statsOf(e).Slow++
+}
+
+func (a *aggregator) statsOf(e Event) *Stats {
+	key := Key{Collection: e.Collection, Operation: e.Operation}
+	s, ok := a.stats[key]
+	if !ok {
+		s = &Stats{Buckets: make([]int64, len(a.buckets))}
+		a.stats[key] = s
+	}
+	return s
+}
+
+// snapshot 返回按 Key 排序的指标副本
+func (a *aggregator) snapshot() ([]Key, map[Key]Stats) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	keys := make([]Key, 0, len(a.stats))
+	stats := make(map[Key]Stats, len(a.stats))
+	for k, s := range a.stats {
+		keys = append(keys, k)
+		c := *s
+		c.Buckets = append([]int64{}, s.Buckets...)
+		stats[k] = c
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Collection != keys[j].Collection {
+			return keys[i].Collection < keys[j].Collection
+		}
+		return keys[i].Operation < keys[j].Operation
+	})
+	return keys, stats
+}
+
+// NewMemoryExporter 创建在内存中保存记录的 Exporter，用于测试
+func NewMemoryExporter(buckets ...float64) *MemoryExporter {
+	return &MemoryExporter{aggregator: newAggregator(buckets)}
+}
+
+// MemoryExporter 保存全部操作记录与汇总指标
+type MemoryExporter struct {
+	aggregator
+	eventsMu   sync.Mutex
+	events     []Event
+	slowEvents []Event
+}
+
+func (m *MemoryExporter) Observe(e Event) {
+	m.observe(e)
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.events = append(m.events, e)
+}
+
+func (m *MemoryExporter) Slow(e Event) {
+	m.slow(e)
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.slowEvents = append(m.slowEvents, e)
+}
+
+// Events 全部操作记录
+func (m *MemoryExporter) Events() []Event {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	return append([]Event{}, m.events...)
+}
+
+// SlowEvents 慢操作记录
+func (m *MemoryExporter) SlowEvents() []Event {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	return append([]Event{}, m.slowEvents...)
+}
+
+// Stats 返回集合上某种操作的汇总指标
+func (m *MemoryExporter) Stats(collection string, operation string) (Stats, bool) {
+	_, stats := m.snapshot()
+	s, ok := stats[Key{Collection: collection, Operation: operation}]
+	return s, ok
+}
+
+// Reset 清空记录
+func (m *MemoryExporter) Reset() {
+	m.mu.Lock()
+	m.stats = map[Key]*Stats{}
+	m.mu.Unlock()
+	m.eventsMu.Lock()
+	m.events, m.slowEvents = nil, nil
+	m.eventsMu.Unlock()
+}
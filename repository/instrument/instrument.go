@@ -0,0 +1,115 @@
+// Package instrument 记录仓储操作的耗时、错误数、文档数与慢操作，并为每次操作创建追踪 span。
+// 指标通过 Exporter 输出，内置内存与 Prometheus 文本格式两种实现；追踪通过 Tracer 接入具体的追踪系统。
+package instrument
+
+import (
+	"context"
+	"time"
+)
+
+// Event 一次仓储操作的记录
+type Event struct {
+	Collection string
+	Operation  string
+	// Duration 操作耗时
+	Duration time.Duration
+	// Documents 返回或影响的文档数
+	Documents int64
+	Err       error
+}
+
+// Exporter 接收操作记录，实现需要并发安全
+type Exporter interface {
+	// Observe 每次操作结束时调用
+	Observe(e Event)
+	// Slow 耗时达到慢操作阈值时额外调用
+	Slow(e Event)
+}
+
+// NewInstrumentation 创建埋点，exporter 为 nil 时只记录追踪
+func NewInstrumentation(exporter Exporter) *Instrumentation {
+	return &Instrumentation{exporter: exporter, now: time.Now}
+}
+
+// Instrumentation 仓储埋点配置
+type Instrumentation struct {
+	exporter      Exporter
+	tracer        Tracer
+	slowThreshold time.Duration
+	now           func() time.Time
+}
+
+// SetTracer 设置追踪器
+func (i *Instrumentation) SetTracer(tracer Tracer) *Instrumentation {
+	i.tracer = tracer
+	return i
+}
+
+// SetSlowThreshold 设置慢操作阈值，<= 0 表示不记录慢操作
+func (i *Instrumentation) SetSlowThreshold(threshold time.Duration) *Instrumentation {
+	i.slowThreshold = threshold
+	return i
+}
+
+// Start 开始记录一次操作，返回携带 span 的 context，操作结束后调用 Operation.End
+func (i *Instrumentation) Start(ctx context.Context, collection string, operation string) (context.Context, *Operation) {
+	op := &Operation{
+		instrumentation: i,
+		event:           Event{Collection: collection, Operation: operation},
+		start:           i.now(),
+	}
+	if nil != i.tracer {
+		ctx, op.span = i.tracer.Start(ctx, collection+"."+operation, map[string]string{
+			"db.system":     "mongodb",
+			"db.collection": collection,
+			"db.operation":  operation,
+		})
+	}
+	return ctx, op
+}
+
+// Operation 进行中的操作
+type Operation struct {
+	instrumentation *Instrumentation
+	event           Event
+	start           time.Time
+	span            Span
+}
+
+// End 结束操作，documents 为返回或影响的文档数
+func (o *Operation) End(documents int64, err error) {
+	i := o.instrumentation
+	o.event.Duration = i.now().Sub(o.start)
+	o.event.Documents = documents
+	o.event.Err = err
+
+	if nil != o.span {
+		o.span.SetAttribute("db.documents", documents)
+		if nil != err {
+			o.span.RecordError(err)
+		}
+		o.span.End()
+	}
+	if nil == i.exporter {
+		return
+	}
+	i.exporter.Observe(o.event)
+	if i.slowThreshold > 0 && o.event.Duration >= i.slowThreshold {
+		i.exporter.Slow(o.event)
+	}
+}
+
+// Exporters 将记录依次发送给多个 Exporter
+type Exporters []Exporter
+
+func (es Exporters) Observe(e Event) {
+	for _, exporter := range es {
+		exporter.Observe(e)
+	}
+}
+
+func (es Exporters) Slow(e Event) {
+	for _, exporter := range es {
+		exporter.Slow(e)
+	}
+}
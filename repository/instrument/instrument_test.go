@@ -0,0 +1,93 @@
+package instrument
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock 每次调用前进 step
+func fakeClock(step time.Duration) func() time.Time {
+	now := time.Unix(0, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestInstrumentation(t *testing.T) {
+	exporter := NewMemoryExporter()
+	tracer := NewMemoryTracer()
+	i := NewInstrumentation(exporter).SetTracer(tracer).SetSlowThreshold(50 * time.Millisecond)
+
+	i.now = fakeClock(10 * time.Millisecond)
+	_, op := i.Start(context.TODO(), "users", "Find")
+	op.End(3, nil)
+
+	i.now = fakeClock(100 * time.Millisecond)
+	parent, root := tracer.Start(context.TODO(), "handler", nil)
+	_, op = i.Start(parent, "users", "Find")
+	op.End(0, errors.New("boom"))
+	root.End()
+
+	s, ok := exporter.Stats("users", "Find")
+	if !ok {
+		t.Fatalf("Stats() not found")
+	}
+	if s.Count != 2 || s.Errors != 1 || s.Documents != 3 || s.Slow != 1 {
+		t.Fatalf("Stats() = %+v", s)
+	}
+	// 0.01s 落入 0.01 桶，0.1s 落入 0.1 桶
+	if s.Buckets[1] != 0 || s.Buckets[2] != 1 || s.Buckets[5] != 2 {
+		t.Fatalf("Buckets = %v", s.Buckets)
+	}
+	if slow := exporter.SlowEvents(); len(slow) != 1 || slow[0].Duration != 100*time.Millisecond {
+		t.Fatalf("SlowEvents() = %+v", slow)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("Spans() = %d, want 3", len(spans))
+	}
+	child, rootSpan := spans[1], spans[2]
+	if child.Name != "users.Find" || child.ParentID != rootSpan.ID || nil == child.Err {
+		t.Fatalf("child span = %+v", child)
+	}
+	if spans[0].ParentID != 0 || spans[0].Attributes["db.documents"] != int64(3) || spans[0].Attributes["db.collection"] != "users" {
+		t.Fatalf("root span = %+v", spans[0])
+	}
+}
+
+func TestPrometheusExporter(t *testing.T) {
+	p := NewPrometheusExporter("app", 0.1, 1)
+	p.Observe(Event{Collection: "users", Operation: "Find", Duration: 50 * time.Millisecond, Documents: 2})
+	p.Observe(Event{Collection: "users", Operation: "Find", Duration: 2 * time.Second, Err: errors.New("boom")})
+	p.Slow(Event{Collection: "users", Operation: "Find", Duration: 2 * time.Second})
+
+	var sb strings.Builder
+	n, err := p.WriteTo(&sb)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := sb.String()
+	if int(n) != len(out) {
+		t.Fatalf("WriteTo() = %d, wrote %d bytes", n, len(out))
+	}
+	for _, line := range []string{
+		"# TYPE app_repository_operation_duration_seconds histogram",
+		`app_repository_operation_duration_seconds_bucket{collection="users",operation="Find",le="0.1"} 1`,
+		`app_repository_operation_duration_seconds_bucket{collection="users",operation="Find",le="1"} 1`,
+		`app_repository_operation_duration_seconds_bucket{collection="users",operation="Find",le="+Inf"} 2`,
+		`app_repository_operation_duration_seconds_sum{collection="users",operation="Find"} 2.05`,
+		`app_repository_operation_duration_seconds_count{collection="users",operation="Find"} 2`,
+		`app_repository_operation_errors_total{collection="users",operation="Find"} 1`,
+		`app_repository_documents_total{collection="users",operation="Find"} 2`,
+		`app_repository_slow_operations_total{collection="users",operation="Find"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("output missing %q\n%s", line, out)
+		}
+	}
+}
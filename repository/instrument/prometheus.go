@@ -0,0 +1,111 @@
+package instrument
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// NewPrometheusExporter 创建以 Prometheus 文本格式输出指标的 Exporter，指标名以 namespace_ 开头
+func NewPrometheusExporter(namespace string, buckets ...float64) *PrometheusExporter {
+	return &PrometheusExporter{aggregator: newAggregator(buckets), namespace: namespace}
+}
+
+// PrometheusExporter 汇总指标并以 Prometheus 文本格式输出，可直接作为 /metrics 的 http.Handler
+type PrometheusExporter struct {
+	aggregator
+	namespace string
+}
+
+func (p *PrometheusExporter) Observe(e Event) {
+	p.observe(e)
+}
+
+func (p *PrometheusExporter) Slow(e Event) {
+	p.slow(e)
+}
+
+// WriteTo 按 Prometheus 文本格式写出全部指标
+func (p *PrometheusExporter) WriteTo(w io.Writer) (int64, error) {
+	keys, stats := p.snapshot()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	duration := p.name("repository_operation_duration_seconds")
+	cw.printf("# HELP %s Repository operation latency in seconds.\n# TYPE %s histogram\n", duration, duration)
+	for _, k := range keys {
+		s := stats[k]
+		l := labels(k)
+		for i, upper := range p.buckets {
+			cw.printf("%s_bucket{%s,le=\"%s\"} %d\n", duration, l, formatFloat(upper), s.Buckets[i])
+		}
+		cw.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", duration, l, s.Count)
+		cw.printf("%s_sum{%s} %s\n", duration, l, formatFloat(s.Sum))
+		cw.printf("%s_count{%s} %d\n", duration, l, s.Count)
+	}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(s Stats) int64
+	}{
+		{"repository_operation_errors_total", "Repository operations that returned an error.", func(s Stats) int64 { return s.Errors }},
+		{"repository_documents_total", "Documents returned or affected by repository operations.", func(s Stats) int64 { return s.Documents }},
+		{"repository_slow_operations_total", "Repository operations slower than the slow threshold.", func(s Stats) int64 { return s.Slow }},
+	}
+	for _, c := range counters {
+		name := p.name(c.name)
+		cw.printf("# HELP %s %s\n# TYPE %s counter\n", name, c.help, name)
+		for _, k := range keys {
+			cw.printf("%s{%s} %d\n", name, labels(k), c.value(stats[k]))
+		}
+	}
+
+	if nil != cw.err {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+func (p *PrometheusExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = p.WriteTo(w)
+}
+
+func (p *PrometheusExporter) name(name string) string {
+	if "" == p.namespace {
+		return name
+	}
+	return p.namespace + "_" + name
+}
+
+func labels(k Key) string {
+	return `collection="` + escapeLabel(k.Collection) + `",operation="` + escapeLabel(k.Operation) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...interface{}) {
+	if nil != c.err {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
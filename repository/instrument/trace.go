@@ -0,0 +1,104 @@
+package instrument
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tracer 创建追踪 span，实现应从 ctx 中读取父 span 并返回携带新 span 的 context
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span 追踪 span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type spanKey struct{}
+
+// ContextWithSpan 返回携带 span 的 context，供没有自带 context 传播机制的 Tracer 使用
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 读取 ContextWithSpan 写入的 span
+func SpanFromContext(ctx context.Context) (Span, bool) {
+	if nil == ctx {
+		return nil, false
+	}
+	span, ok := ctx.Value(spanKey{}).(Span)
+	return span, ok
+}
+
+// NewMemoryTracer 创建在内存中记录 span 的追踪器，用于测试
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{now: time.Now}
+}
+
+// MemoryTracer 在内存中记录已结束的 span
+type MemoryTracer struct {
+	mu     sync.Mutex
+	nextId atomic.Uint64
+	spans  []*MemorySpan
+	now    func() time.Time
+}
+
+// MemorySpan MemoryTracer 记录的 span，ParentID 为 0 表示根 span
+type MemorySpan struct {
+	ID         uint64
+	ParentID   uint64
+	Name       string
+	Attributes map[string]interface{}
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+
+	tracer *MemoryTracer
+}
+
+func (t *MemoryTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	span := &MemorySpan{
+		ID:         t.nextId.Add(1),
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		StartTime:  t.now(),
+		tracer:     t,
+	}
+	if parent, ok := SpanFromContext(ctx); ok {
+		if p, ok := parent.(*MemorySpan); ok {
+			span.ParentID = p.ID
+		}
+	}
+	for k, v := range attributes {
+		span.Attributes[k] = v
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// Spans 返回已结束的 span，按结束顺序排列
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan{}, t.spans...)
+}
+
+func (s *MemorySpan) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.Err = err
+}
+
+func (s *MemorySpan) End() {
+	t := s.tracer
+	s.EndTime = t.now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, s)
+}
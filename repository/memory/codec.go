@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+// encode 使用 registry 将值编码为 bson.M（嵌套文档为保留字段顺序的 bson.D，见 matcher.Normalize）
+func encode(registry *bsoncodec.Registry, v interface{}) (bson.M, error) {
+	return matcher.Normalize(registry, v)
+}
+
+// decode 使用 registry 将 bson 字节解码到 out
+func decode(registry *bsoncodec.Registry, data []byte, out interface{}) error {
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+	if err != nil {
+		return err
+	}
+	if err := dec.SetRegistry(registry); err != nil {
+		return err
+	}
+	return dec.Decode(out)
+}
+
+// toEntity 将存储的文档转换为实体
+func toEntity[Entity interface{}](registry *bsoncodec.Registry, doc bson.M) (*Entity, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var result Entity
+	if err := decode(registry, data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,503 @@
+package memory
+
+import (
+	"context"
+	gosort "sort"
+	"sync"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/page"
+	"github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/mongo/mongoxcodec"
+	mongorepo "github.com/aomi-go/data/repository/mongo"
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ mongorepo.Repository[struct{}] = (*Repository[struct{}])(nil)
+
+// NewRepository 创建基于内存的仓储，使用 mongoxcodec.NewRegistry 进行编解码
+func NewRepository[E interface{}]() *Repository[E] {
+	return NewRepositoryWithRegistry[E](mongoxcodec.NewRegistry())
+}
+
+// NewRepositoryWithRegistry 使用指定的 Registry 创建基于内存的仓储，应与 mongo.Client 使用的 Registry 保持一致
+func NewRepositoryWithRegistry[E interface{}](registry *bsoncodec.Registry) *Repository[E] {
+	return &Repository[E]{
+		registry: registry,
+		docs:     map[interface{}]bson.M{},
+	}
+}
+
+// Repository 基于内存的 mongo.Repository 实现，ID 规则与 mongo.DocumentRepository 一致，主要用于单元测试
+type Repository[Entity interface{}] struct {
+	mu       sync.RWMutex
+	registry *bsoncodec.Registry
+	// docs 以 _id 为键，upsert 时 _id 可以不是 ObjectID
+	docs map[interface{}]bson.M
+	// ids 保存插入顺序，作为未指定排序时的自然顺序
+	ids []interface{}
+}
+
+func (r *Repository[Entity]) Save(ctx context.Context, entity *Entity) (*Entity, error) {
+	doc, err := r.prepare(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.put(doc)
+	return entity, nil
+}
+
+func (r *Repository[Entity]) SaveMany(ctx context.Context, entities []*Entity) ([]*Entity, error) {
+	docs := make([]bson.M, 0, len(entities))
+	for _, entity := range entities {
+		doc, err := r.prepare(entity)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, doc := range docs {
+		r.put(doc)
+	}
+	return entities, nil
+}
+
+func (r *Repository[Entity]) FindAll(ctx context.Context) ([]*Entity, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+func (r *Repository[Entity]) FindAllById(ctx context.Context, ids ...interface{}) ([]*Entity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*Entity, 0)
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		oid, ok := mongorepo.ToObjectIdWithCheck(id)
+		if !ok || oid.IsZero() || seen[oid] {
+			continue
+		}
+		seen[oid] = true
+		if doc, ok := r.docs[oid]; ok {
+			entity, err := toEntity[Entity](r.registry, doc)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, entity)
+		}
+	}
+	return result, nil
+}
+
+func (r *Repository[Entity]) FindById(ctx context.Context, id interface{}) (*Entity, error) {
+	oid, _ := mongorepo.ToObjectIdWithCheck(id)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	doc, ok := r.docs[oid]
+	if !ok {
+		return nil, common.ErrNoResult
+	}
+	return toEntity[Entity](r.registry, doc)
+}
+
+func (r *Repository[Entity]) ExistsById(ctx context.Context, id interface{}) (bool, error) {
+	oid, _ := mongorepo.ToObjectIdWithCheck(id)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.docs[oid]
+	return ok, nil
+}
+
+func (r *Repository[Entity]) DeleteById(ctx context.Context, id interface{}) (bool, error) {
+	oid, _ := mongorepo.ToObjectIdWithCheck(id)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.docs[oid]; !ok {
+		return false, nil
+	}
+	r.remove(oid)
+	return true, nil
+}
+
+func (r *Repository[Entity]) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]*Entity, error) {
+	q := query{}
+	for _, opt := range opts {
+		if nil == opt {
+			continue
+		}
+		if nil != opt.Sort {
+			q.sort = opt.Sort
+		}
+		if nil != opt.Skip {
+			q.skip = *opt.Skip
+		}
+		if nil != opt.Limit {
+			q.limit = *opt.Limit
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.match(filter, q)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Entity, 0, len(docs))
+	for _, doc := range docs {
+		entity, err := toEntity[Entity](r.registry, doc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, entity)
+	}
+	return result, nil
+}
+
+func (r *Repository[Entity]) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) (*Entity, error) {
+	q := query{limit: 1}
+	for _, opt := range opts {
+		if nil == opt {
+			continue
+		}
+		if nil != opt.Sort {
+			q.sort = opt.Sort
+		}
+		if nil != opt.Skip {
+			q.skip = *opt.Skip
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.match(filter, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, common.ErrNoResult
+	}
+	return toEntity[Entity](r.registry, docs[0])
+}
+
+func (r *Repository[Entity]) FindOneAndModify(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (*Entity, error) {
+	q := query{limit: 1}
+	upsert := false
+	returnAfter := false
+	for _, opt := range opts {
+		if nil == opt {
+			continue
+		}
+		if nil != opt.Sort {
+			q.sort = opt.Sort
+		}
+		if nil != opt.Upsert {
+			upsert = *opt.Upsert
+		}
+		if nil != opt.ReturnDocument {
+			returnAfter = *opt.ReturnDocument == options.After
+		}
+	}
+
+	updateDoc, err := encode(r.registry, update)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	docs, err := r.match(filter, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(docs) == 0 {
+		if !upsert {
+			return nil, common.ErrNoResult
+		}
+		filterDoc, err := encode(r.registry, filter)
+		if err != nil {
+			return nil, err
+		}
+		doc := upsertDocument(filterDoc)
+		if err := applyUpdate(doc, updateDoc); err != nil {
+			return nil, err
+		}
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = primitive.NewObjectID()
+		}
+		r.put(doc)
+		if !returnAfter {
+			return nil, common.ErrNoResult
+		}
+		return toEntity[Entity](r.registry, doc)
+	}
+
+	before, err := toEntity[Entity](r.registry, docs[0])
+	if err != nil {
+		return nil, err
+	}
+	// 在副本上执行更新，避免更新失败时留下部分修改
+	doc, err := encode(r.registry, docs[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := applyUpdate(doc, updateDoc); err != nil {
+		return nil, err
+	}
+	r.put(doc)
+	if !returnAfter {
+		return before, nil
+	}
+	return toEntity[Entity](r.registry, doc)
+}
+
+func (r *Repository[Entity]) Count(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	q := query{}
+	for _, opt := range opts {
+		if nil == opt {
+			continue
+		}
+		if nil != opt.Skip {
+			q.skip = *opt.Skip
+		}
+		if nil != opt.Limit {
+			q.limit = *opt.Limit
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs, err := r.match(filter, q)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+func (r *Repository[Entity]) Exist(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (bool, error) {
+	if count, err := r.Count(ctx, filter, opts...); nil == err {
+		return count > 0, nil
+	} else {
+		return false, err
+	}
+}
+
+func (r *Repository[Entity]) Delete(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	docs, err := r.match(filter, query{})
+	if err != nil {
+		return 0, err
+	}
+	for _, doc := range docs {
+		r.remove(doc["_id"])
+	}
+	return int64(len(docs)), nil
+}
+
+// QueryWithPage 分页查询
+func (r *Repository[Entity]) QueryWithPage(ctx context.Context, filter interface{}, pageable *page.Pageable) (*page.Page[Entity], error) {
+	if nil == pageable {
+		pageable = page.NewDefaultPageable()
+	}
+	total, err := r.Count(ctx, filter)
+	if nil != err {
+		return nil, err
+	}
+
+	if total == 0 {
+		return page.NewPage[Entity](make([]*Entity, 0), 0, pageable), nil
+	}
+
+	pageOpts := options.Find().SetSkip(pageable.GetOffset()).SetLimit(int64(pageable.GetSize()))
+	sortOpts := mongorepo.GetSortOpts(pageable.Sort)
+
+	entities, err := r.Find(ctx, filter, pageOpts, sortOpts)
+	if nil != err {
+		return nil, err
+	}
+
+	return page.NewPage[Entity](entities, total, pageable), nil
+}
+
+// QueryWithSlice 分片查询，多查询一条数据判断是否还有下一页，不统计总数
+func (r *Repository[Entity]) QueryWithSlice(ctx context.Context, filter interface{}, pageable *page.Pageable) (*page.Slice[Entity], error) {
+	if nil == pageable {
+		pageable = page.NewDefaultPageable()
+	}
+
+	pageOpts := options.Find().SetSkip(pageable.GetOffset()).SetLimit(int64(pageable.GetSize()) + 1)
+	sortOpts := mongorepo.GetSortOpts(pageable.Sort)
+
+	entities, err := r.Find(ctx, filter, pageOpts, sortOpts)
+	if nil != err {
+		return nil, err
+	}
+
+	hasNext := len(entities) > pageable.GetSize()
+	if hasNext {
+		entities = entities[:pageable.GetSize()]
+	}
+	return page.NewSlice[Entity](entities, hasNext, pageable), nil
+}
+
+// QueryWithSort 排序查询
+func (r *Repository[Entity]) QueryWithSort(ctx context.Context, filter interface{}, sort *sort.Sort) ([]*Entity, error) {
+	var opts *options.FindOptions
+	if nil != sort {
+		opts = mongorepo.GetSortOpts(*sort)
+	}
+	return r.Find(ctx, filter, opts)
+}
+
+// prepare 按 mongo.DocumentRepository 的规则分配 ID，并将实体编码为文档
+func (r *Repository[Entity]) prepare(entity *Entity) (bson.M, error) {
+	idFieldValue, idFieldOk := mongorepo.GetIdFieldValue(entity, "ID")
+	idOk := false
+	var id primitive.ObjectID
+	if idFieldOk {
+		id, idOk = mongorepo.ToObjectIdWithCheck(idFieldValue.Interface())
+	}
+	if !idOk || id.IsZero() {
+		id = primitive.NewObjectID()
+		if idFieldOk {
+			mongorepo.SetIdFieldValue(idFieldValue, id)
+		}
+	}
+
+	doc, err := encode(r.registry, entity)
+	if err != nil {
+		return nil, err
+	}
+	doc["_id"] = id
+	return doc, nil
+}
+
+// put 插入或替换文档，调用方需持有写锁
+func (r *Repository[Entity]) put(doc bson.M) {
+	id := doc["_id"]
+	if _, ok := r.docs[id]; !ok {
+		r.ids = append(r.ids, id)
+	}
+	r.docs[id] = doc
+}
+
+// remove 删除文档，调用方需持有写锁
+func (r *Repository[Entity]) remove(id interface{}) {
+	delete(r.docs, id)
+	for i, v := range r.ids {
+		if v == id {
+			r.ids = append(r.ids[:i], r.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+type query struct {
+	sort  interface{}
+	skip  int64
+	limit int64
+}
+
+// match 返回满足条件的文档（已排序、分页），调用方需持有锁
+func (r *Repository[Entity]) match(filter interface{}, q query) ([]bson.M, error) {
+	m, err := matcher.NewWithRegistry(r.registry, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]bson.M, 0)
+	for _, id := range r.ids {
+		if doc := r.docs[id]; m.MatchDocument(doc) {
+			result = append(result, doc)
+		}
+	}
+
+	if nil != q.sort {
+		keys, err := sortKeys(r.registry, q.sort)
+		if err != nil {
+			return nil, err
+		}
+		gosort.SliceStable(result, func(i, j int) bool {
+			for _, key := range keys {
+				a, _ := matcher.Lookup(result[i], key.Key)
+				b, _ := matcher.Lookup(result[j], key.Key)
+				if c := matcher.Compare(a, b); c != 0 {
+					return c*key.Value.(int) < 0
+				}
+			}
+			return false
+		})
+	}
+
+	if q.skip > 0 {
+		if q.skip >= int64(len(result)) {
+			return []bson.M{}, nil
+		}
+		result = result[q.skip:]
+	}
+	if q.limit > 0 && q.limit < int64(len(result)) {
+		result = result[:q.limit]
+	}
+	return result, nil
+}
+
+// sortKeys 将排序条件解析为有序的字段与方向（1 或 -1）
+func sortKeys(registry *bsoncodec.Registry, s interface{}) (bson.D, error) {
+	var raw bson.D
+	switch v := s.(type) {
+	case bson.D:
+		raw = v
+	default:
+		m, err := encode(registry, s)
+		if err != nil {
+			return nil, err
+		}
+		for k, val := range m {
+			raw = append(raw, bson.E{Key: k, Value: val})
+		}
+	}
+
+	keys := make(bson.D, 0, len(raw))
+	for _, e := range raw {
+		direction := 1
+		if matcher.Compare(toNumber(e.Value), int32(0)) < 0 {
+			direction = -1
+		}
+		keys = append(keys, bson.E{Key: e.Key, Value: direction})
+	}
+	return keys, nil
+}
+
+func toNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int8:
+		return int32(n)
+	case int16:
+		return int32(n)
+	case uint8:
+		return int32(n)
+	case uint16:
+		return int32(n)
+	case uint32:
+		return int64(n)
+	case float32:
+		return float64(n)
+	}
+	return v
+}
@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/page"
+	"github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type User struct {
+	ID     mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	UserId mongoxentity.StrObjectId `bson:"user_id,omitempty"`
+	Name   string                   `bson:"name"`
+	Age    int                      `bson:"age"`
+}
+
+func TestSaveAndFindById(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepository[User]()
+
+	saved, err := repo.Save(ctx, &User{Name: "a"})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved.ID.IsZero() {
+		t.Fatalf("Save() did not assign an id")
+	}
+
+	for _, id := range []interface{}{saved.ID, saved.ID.String(), saved.ID.ObjectId()} {
+		found, err := repo.FindById(ctx, id)
+		if err != nil {
+			t.Fatalf("FindById(%v) error = %v", id, err)
+		}
+		if found.Name != "a" {
+			t.Errorf("FindById(%v) name = %s", id, found.Name)
+		}
+	}
+
+	saved.Name = "b"
+	if _, err := repo.Save(ctx, saved); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if count, _ := repo.Count(ctx, bson.M{}); count != 1 {
+		t.Errorf("Count() = %d, want 1", count)
+	}
+
+	if _, err := repo.FindById(ctx, primitive.NewObjectID()); !errors.Is(err, common.ErrNoResult) {
+		t.Errorf("FindById() error = %v, want ErrNoResult", err)
+	}
+}
+
+func TestFindWithFilterAndPage(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepository[User]()
+	owner := mongoxentity.NewStrObjectId()
+
+	var users []*User
+	for i := 0; i < 5; i++ {
+		users = append(users, &User{UserId: owner, Name: fmt.Sprintf("u-%d", i), Age: i})
+	}
+	users = append(users, &User{Name: "other", Age: 10})
+	if _, err := repo.SaveMany(ctx, users); err != nil {
+		t.Fatalf("SaveMany() error = %v", err)
+	}
+
+	found, err := repo.Find(ctx, bson.M{"user_id": owner})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if len(found) != 5 {
+		t.Errorf("Find() returned %d, want 5", len(found))
+	}
+
+	p, err := repo.QueryWithPage(ctx, bson.M{"user_id": owner}, page.NewPageableWithSort(1, 2, sort.NewSortBy(sort.DESC, "age")))
+	if err != nil {
+		t.Fatalf("QueryWithPage() error = %v", err)
+	}
+	if p.TotalElements != 5 || p.TotalPages != 3 || len(p.Content) != 2 {
+		t.Fatalf("QueryWithPage() = %+v", p)
+	}
+	if p.Content[0].Age != 2 || p.Content[1].Age != 1 {
+		t.Errorf("QueryWithPage() content ages = %d,%d, want 2,1", p.Content[0].Age, p.Content[1].Age)
+	}
+
+	for number, hasNext := range map[int]bool{1: true, 2: false} {
+		s, err := repo.QueryWithSlice(ctx, bson.M{"user_id": owner}, page.NewPageableWithSort(number, 2, sort.NewSortBy(sort.DESC, "age")))
+		if err != nil {
+			t.Fatalf("QueryWithSlice() error = %v", err)
+		}
+		if s.HasNext != hasNext || s.Number != number {
+			t.Errorf("QueryWithSlice(%d) = %+v", number, s)
+		}
+	}
+
+	all, err := repo.FindAllById(ctx, users[0].ID, users[5].ID.String(), "invalid")
+	if err != nil || len(all) != 2 {
+		t.Errorf("FindAllById() = %d, %v", len(all), err)
+	}
+}
+
+func TestModifyAndDelete(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepository[User]()
+	u, _ := repo.Save(ctx, &User{Name: "a", Age: 1})
+
+	updated, err := repo.FindOneAndModify(ctx, bson.M{"name": "a"}, bson.M{"$inc": bson.M{"age": 2}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if err != nil {
+		t.Fatalf("FindOneAndModify() error = %v", err)
+	}
+	if updated.Age != 3 {
+		t.Errorf("FindOneAndModify() age = %d, want 3", updated.Age)
+	}
+
+	if ok, _ := repo.DeleteById(ctx, u.ID); !ok {
+		t.Errorf("DeleteById() = false")
+	}
+	if ok, _ := repo.ExistsById(ctx, u.ID); ok {
+		t.Errorf("ExistsById() after delete = true")
+	}
+}
+
+func TestFindOneAndModifyUpsert(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepository[User]()
+	userId := primitive.NewObjectID()
+	filter := bson.M{
+		"name": "a",
+		"age":  bson.M{"$gt": 10},
+		"$and": bson.A{bson.M{"user_id": bson.M{"$eq": userId}}, bson.M{"age": bson.M{"$lt": 20}}},
+		"$or":  bson.A{bson.M{"name": "b"}},
+	}
+	u, err := repo.FindOneAndModify(ctx, filter, bson.M{"$set": bson.M{"age": 18}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After))
+	if err != nil {
+		t.Fatalf("FindOneAndModify() error = %v", err)
+	}
+	if u.Name != "a" || u.Age != 18 || u.UserId.String() != userId.Hex() || u.ID.IsZero() {
+		t.Errorf("FindOneAndModify() = %+v", u)
+	}
+	doc := repo.docs[repo.ids[0]]
+	for _, key := range []string{"$and", "$or"} {
+		if _, ok := doc[key]; ok {
+			t.Errorf("upserted document contains %s: %v", key, doc)
+		}
+	}
+}
+
+func TestFindOneAndModifyUpsertId(t *testing.T) {
+	ctx := context.TODO()
+	repo := NewRepository[struct {
+		ID    string `bson:"_id"`
+		Count int    `bson:"count"`
+	}]()
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	for i := 1; i <= 2; i++ {
+		counter, err := repo.FindOneAndModify(ctx, bson.M{"_id": "orders"}, bson.M{"$inc": bson.M{"count": 1}}, opts)
+		if err != nil {
+			t.Fatalf("FindOneAndModify() error = %v", err)
+		}
+		if counter.ID != "orders" || counter.Count != i {
+			t.Errorf("FindOneAndModify() = %+v, want count %d", counter, i)
+		}
+	}
+}
+
+func TestEmbeddedDocument(t *testing.T) {
+	type address struct {
+		City string `bson:"city"`
+		Zip  string `bson:"zip"`
+	}
+	type customer struct {
+		ID      primitive.ObjectID `bson:"_id,omitempty"`
+		Address address            `bson:"address"`
+	}
+	ctx := context.TODO()
+	repo := NewRepository[customer]()
+	c, _ := repo.Save(ctx, &customer{Address: address{City: "Shanghai", Zip: "200000"}})
+
+	// 内嵌文档按字段顺序比较
+	if ok, _ := repo.Exist(ctx, bson.M{"address": bson.D{{Key: "city", Value: "Shanghai"}, {Key: "zip", Value: "200000"}}}); !ok {
+		t.Error("Exist() with same field order = false")
+	}
+	if ok, _ := repo.Exist(ctx, bson.M{"address": bson.D{{Key: "zip", Value: "200000"}, {Key: "city", Value: "Shanghai"}}}); ok {
+		t.Error("Exist() with different field order = true")
+	}
+
+	updated, err := repo.FindOneAndModify(ctx, bson.M{"_id": c.ID}, bson.M{"$set": bson.M{"address.city": "Beijing"}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After))
+	if err != nil || updated.Address.City != "Beijing" || updated.Address.Zip != "200000" {
+		t.Errorf("FindOneAndModify() nested $set = %+v, %v", updated, err)
+	}
+}
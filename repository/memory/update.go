@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applyUpdate 将更新操作应用到文档上，支持 $set、$unset、$inc
+func applyUpdate(doc bson.M, update bson.M) error {
+	for op, arg := range update {
+		fields, ok := matcher.ToM(arg)
+		if !ok {
+			return fmt.Errorf("memory: update operator %s requires a document", op)
+		}
+		for path, value := range fields {
+			if path == "_id" {
+				return fmt.Errorf("memory: field _id is immutable")
+			}
+			switch op {
+			case "$set":
+				setPath(doc, path, value)
+			case "$unset":
+				unsetPath(doc, path)
+			case "$inc":
+				current, _ := matcher.Lookup(doc, path)
+				sum, err := add(current, value)
+				if err != nil {
+					return err
+				}
+				setPath(doc, path, sum)
+			default:
+				return fmt.Errorf("memory: unsupported update operator %s", op)
+			}
+		}
+	}
+	return nil
+}
+
+// upsertDocument upsert 未匹配到文档时的初始文档：只取顶层与 $and 中的相等条件，其他操作符条件不写入文档
+func upsertDocument(filter bson.M) bson.M {
+	doc := bson.M{}
+	seedEquality(doc, filter)
+	return doc
+}
+
+func seedEquality(doc bson.M, filter bson.M) {
+	for key, value := range filter {
+		if "$and" == key {
+			if conditions, ok := value.(bson.A); ok {
+				for _, c := range conditions {
+					if m, ok := matcher.ToM(c); ok {
+						seedEquality(doc, m)
+					}
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		if m, ok := matcher.ToM(value); ok && isOperator(m) {
+			eq, ok := m["$eq"]
+			if !ok {
+				continue
+			}
+			value = eq
+		}
+		setPath(doc, key, value)
+	}
+}
+
+// isOperator 文档的字段均为操作符，如 {"$gt": 1}
+func isOperator(m bson.M) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+func setPath(doc bson.M, path string, value interface{}) {
+	parts := strings.Split(path, ".")
+	doc[parts[0]] = setIn(doc[parts[0]], parts[1:], value)
+}
+
+// setIn 在内嵌文档中按路径设置值并返回修改后的文档，路径上不是文档的值替换为新的 bson.D
+func setIn(current interface{}, parts []string, value interface{}) interface{} {
+	if len(parts) == 0 {
+		return value
+	}
+	switch d := current.(type) {
+	case bson.M:
+		d[parts[0]] = setIn(d[parts[0]], parts[1:], value)
+		return d
+	case bson.D:
+		for i := range d {
+			if d[i].Key == parts[0] {
+				d[i].Value = setIn(d[i].Value, parts[1:], value)
+				return d
+			}
+		}
+		return append(d, bson.E{Key: parts[0], Value: setIn(nil, parts[1:], value)})
+	}
+	return bson.D{{Key: parts[0], Value: setIn(nil, parts[1:], value)}}
+}
+
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	if len(parts) == 1 {
+		delete(doc, path)
+		return
+	}
+	if v, ok := doc[parts[0]]; ok {
+		doc[parts[0]] = unsetIn(v, parts[1:])
+	}
+}
+
+// unsetIn 在内嵌文档中按路径删除字段并返回修改后的文档
+func unsetIn(current interface{}, parts []string) interface{} {
+	switch d := current.(type) {
+	case bson.M:
+		if len(parts) == 1 {
+			delete(d, parts[0])
+		} else if v, ok := d[parts[0]]; ok {
+			d[parts[0]] = unsetIn(v, parts[1:])
+		}
+	case bson.D:
+		for i := range d {
+			if d[i].Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				return append(d[:i], d[i+1:]...)
+			}
+			d[i].Value = unsetIn(d[i].Value, parts[1:])
+			break
+		}
+	}
+	return current
+}
+
+func add(current interface{}, delta interface{}) (interface{}, error) {
+	if current == nil {
+		current = int32(0)
+	}
+	switch c := current.(type) {
+	case int32:
+		switch d := delta.(type) {
+		case int32:
+			return c + d, nil
+		case int64:
+			return int64(c) + d, nil
+		case float64:
+			return float64(c) + d, nil
+		}
+	case int64:
+		switch d := delta.(type) {
+		case int32:
+			return c + int64(d), nil
+		case int64:
+			return c + d, nil
+		case float64:
+			return float64(c) + d, nil
+		}
+	case float64:
+		switch d := delta.(type) {
+		case int32:
+			return c + float64(d), nil
+		case int64:
+			return c + float64(d), nil
+		case float64:
+			return c + d, nil
+		}
+	case primitive.Decimal128:
+		return nil, fmt.Errorf("memory: $inc on decimal128 is not supported")
+	}
+	return nil, fmt.Errorf("memory: cannot apply $inc to %T with %T", current, delta)
+}
@@ -0,0 +1,452 @@
+// Package migration 按顺序执行以 Go 函数注册的 MongoDB 数据迁移。
+//
+// 已执行的迁移记录在 _migrations 集合中，执行期间通过 _migrations_lock 集合中的锁文档保证只有一个进程在迁移
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	mongox "github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrLocked 其他进程正在执行迁移
+	ErrLocked = errors.New("migration: locked by another process")
+	// ErrIrreversible 迁移没有提供 Down，无法回滚
+	ErrIrreversible = errors.New("migration: migration is irreversible")
+	// ErrUnknownMigration 目标迁移未注册
+	ErrUnknownMigration = errors.New("migration: unknown migration")
+	// ErrLockLost 执行期间迁移锁过期并被其他进程获取
+	ErrLockLost = errors.New("migration: lock lost")
+)
+
+const (
+	// DefaultCollection 迁移记录的默认集合
+	DefaultCollection = "_migrations"
+	// DefaultLockCollection 迁移锁的默认集合
+	DefaultLockCollection = "_migrations_lock"
+
+	lockId = "lock"
+)
+
+// Func 迁移函数，在事务中执行时 ctx 为 mongo.SessionContext
+type Func func(ctx context.Context, db *mongo.Database) error
+
+// Migration 一个迁移，按 ID 的字典序执行，建议使用 20240102150405_add_user_index 形式的 ID
+type Migration struct {
+	ID          string
+	Description string
+	Up          Func
+	// Down 回滚，为空时无法回滚
+	Down Func
+	// Transactional 在 mongox.WithTransaction 中执行迁移并写入记录，需要先调用 mongox.InitTransaction
+	Transactional bool
+}
+
+// Record 已执行迁移的记录
+type Record struct {
+	ID          string    `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	AppliedBy   string    `bson:"applied_by"`
+	// Duration 执行耗时，单位毫秒
+	Duration int64 `bson:"duration"`
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	ID          string
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+	// Registered 为 false 表示集合中有记录但代码中未注册
+	Registered bool
+}
+
+// NewMigrator 创建迁移执行器
+func NewMigrator(db *mongo.Database) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:             db,
+		collection:     DefaultCollection,
+		lockCollection: DefaultLockCollection,
+		lockTTL:        10 * time.Minute,
+		owner:          fmt.Sprintf("%s-%d", host, os.Getpid()),
+		migrations:     map[string]Migration{},
+		clock:          time.Now,
+	}
+}
+
+type Migrator struct {
+	db             *mongo.Database
+	collection     string
+	lockCollection string
+	// lockTTL 锁的过期时间，执行期间每 lockTTL/3 续期一次，持有锁的进程异常退出后其他进程可在过期后重新获取
+	lockTTL    time.Duration
+	owner      string
+	migrations map[string]Migration
+	// store 为空时使用 db 中的集合
+	store store
+	clock func() time.Time
+}
+
+// SetCollection 设置迁移记录的集合
+func (m *Migrator) SetCollection(name string) *Migrator {
+	m.collection = name
+	return m
+}
+
+// SetLockCollection 设置迁移锁的集合
+func (m *Migrator) SetLockCollection(name string) *Migrator {
+	m.lockCollection = name
+	return m
+}
+
+// SetLockTTL 设置锁的过期时间，执行期间每 ttl/3 续期一次，续期失败或锁已被其他进程获取时中止迁移
+func (m *Migrator) SetLockTTL(ttl time.Duration) *Migrator {
+	m.lockTTL = ttl
+	return m
+}
+
+// SetOwner 设置锁与记录中的执行者，默认为 hostname-pid
+func (m *Migrator) SetOwner(owner string) *Migrator {
+	m.owner = owner
+	return m
+}
+
+// Register 注册迁移，ID 为空、重复或没有 Up 时返回错误
+func (m *Migrator) Register(migrations ...Migration) error {
+	for _, migration := range migrations {
+		if "" == migration.ID {
+			return errors.New("migration: empty id")
+		}
+		if nil == migration.Up {
+			return fmt.Errorf("migration: %s has no Up", migration.ID)
+		}
+		if _, ok := m.migrations[migration.ID]; ok {
+			return fmt.Errorf("migration: duplicate id %s", migration.ID)
+		}
+		m.migrations[migration.ID] = migration
+	}
+	return nil
+}
+
+// MustRegister 与 Register 相同，出错时 panic，用于 init 中注册
+func (m *Migrator) MustRegister(migrations ...Migration) *Migrator {
+	if err := m.Register(migrations...); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// sorted 按 ID 排序的迁移
+func (m *Migrator) sorted() []Migration {
+	result := make([]Migration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		result = append(result, migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Status 所有迁移的执行状态，按 ID 排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.storage().applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.status(applied), nil
+}
+
+func (m *Migrator) status(applied map[string]Record) []Status {
+	result := make([]Status, 0, len(m.migrations)+len(applied))
+	for _, migration := range m.sorted() {
+		s := Status{ID: migration.ID, Description: migration.Description, Registered: true}
+		if r, ok := applied[migration.ID]; ok {
+			s.Applied, s.AppliedAt = true, r.AppliedAt
+		}
+		result = append(result, s)
+	}
+	for id, r := range applied {
+		if _, ok := m.migrations[id]; !ok {
+			result = append(result, Status{ID: id, Description: r.Description, Applied: true, AppliedAt: r.AppliedAt})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Up 按顺序执行未执行的迁移，直到 target（包含），target 为空时执行全部。
+// 返回执行的迁移 ID，dryRun 时只返回将要执行的迁移。某个迁移失败时停止并返回已执行的迁移与错误
+func (m *Migrator) Up(ctx context.Context, target string, dryRun bool) ([]string, error) {
+	if "" != target {
+		if _, ok := m.migrations[target]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMigration, target)
+		}
+	}
+	return m.run(ctx, dryRun, func(applied map[string]Record) ([]Migration, error) {
+		return m.pending(applied, target), nil
+	}, m.up)
+}
+
+// Down 按倒序回滚最近执行的 steps 个迁移，steps 为负数时返回错误。
+// 返回回滚的迁移 ID，dryRun 时只返回将要回滚的迁移；待回滚的迁移中有无法回滚的迁移时不执行任何回滚
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]string, error) {
+	if steps < 0 {
+		return nil, fmt.Errorf("migration: invalid steps %d", steps)
+	}
+	return m.run(ctx, dryRun, func(applied map[string]Record) ([]Migration, error) {
+		return m.rollback(applied, steps)
+	}, m.down)
+}
+
+func (m *Migrator) run(ctx context.Context, dryRun bool, plan func(map[string]Record) ([]Migration, error), exec func(context.Context, Migration) error) ([]string, error) {
+	if !dryRun {
+		if err := m.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer m.unlock(context.WithoutCancel(ctx))
+
+		// 锁丢失时取消 ctx，中止正在执行的迁移
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			m.heartbeat(ctx, cancel)
+		}()
+		defer func() {
+			cancel(nil)
+			<-stopped
+		}()
+	}
+
+	applied, err := m.storage().applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := plan(applied)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]string, 0, len(migrations))
+	for _, migration := range migrations {
+		if !dryRun {
+			// 每个迁移执行前确认仍持有锁
+			if err := m.renew(ctx); err != nil {
+				return done, fmt.Errorf("migration: %s: %w", migration.ID, err)
+			}
+			if err := exec(ctx, migration); err != nil {
+				if cause := context.Cause(ctx); errors.Is(cause, ErrLockLost) {
+					err = cause
+				}
+				return done, fmt.Errorf("migration: %s: %w", migration.ID, err)
+			}
+		}
+		done = append(done, migration.ID)
+	}
+	return done, nil
+}
+
+// pending 未执行的迁移，直到 target（包含）
+func (m *Migrator) pending(applied map[string]Record, target string) []Migration {
+	var result []Migration
+	for _, migration := range m.sorted() {
+		if "" != target && migration.ID > target {
+			break
+		}
+		if _, ok := applied[migration.ID]; !ok {
+			result = append(result, migration)
+		}
+	}
+	return result
+}
+
+// rollback 最近执行的 steps 个迁移，倒序
+func (m *Migrator) rollback(applied map[string]Record, steps int) ([]Migration, error) {
+	ids := make([]string, 0, len(applied))
+	for id := range applied {
+		ids = append(ids, id)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	if steps < len(ids) {
+		ids = ids[:steps]
+	}
+
+	result := make([]Migration, 0, len(ids))
+	for _, id := range ids {
+		migration, ok := m.migrations[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMigration, id)
+		}
+		if nil == migration.Down {
+			return nil, fmt.Errorf("%w: %s", ErrIrreversible, id)
+		}
+		result = append(result, migration)
+	}
+	return result, nil
+}
+
+func (m *Migrator) up(ctx context.Context, migration Migration) error {
+	return m.exec(ctx, migration.Transactional, func(ctx context.Context) error {
+		start := m.clock()
+		if err := migration.Up(ctx, m.db); err != nil {
+			return err
+		}
+		now := m.clock()
+		return m.storage().insert(ctx, Record{
+			ID:          migration.ID,
+			Description: migration.Description,
+			AppliedAt:   now,
+			AppliedBy:   m.owner,
+			Duration:    now.Sub(start).Milliseconds(),
+		})
+	})
+}
+
+func (m *Migrator) down(ctx context.Context, migration Migration) error {
+	return m.exec(ctx, migration.Transactional, func(ctx context.Context) error {
+		if err := migration.Down(ctx, m.db); err != nil {
+			return err
+		}
+		return m.storage().delete(ctx, migration.ID)
+	})
+}
+
+// exec 非事务迁移在迁移函数成功后写入记录，写入失败时需人工处理
+func (m *Migrator) exec(ctx context.Context, transactional bool, fn func(ctx context.Context) error) error {
+	if !transactional {
+		return fn(ctx)
+	}
+	_, err := mongox.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// lock 获取迁移锁：锁不存在或已过期时写入当前进程，否则返回 ErrLocked
+func (m *Migrator) lock(ctx context.Context) error {
+	now := m.clock()
+	return m.storage().lock(ctx, m.owner, now, now.Add(m.lockTTL))
+}
+
+// renew 确认当前进程仍持有未过期的锁并续期，否则返回 ErrLockLost
+func (m *Migrator) renew(ctx context.Context) error {
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	now := m.clock()
+	return m.storage().renew(ctx, m.owner, now, now.Add(m.lockTTL))
+}
+
+// heartbeat 每 lockTTL/3 续期一次直到 ctx 结束，锁丢失时以 ErrLockLost 取消 ctx；其他错误在下次续期时重试
+func (m *Migrator) heartbeat(ctx context.Context, cancel context.CancelCauseFunc) {
+	interval := m.lockTTL / 3
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.renew(ctx); errors.Is(err, ErrLockLost) {
+				cancel(err)
+				return
+			}
+		}
+	}
+}
+
+func (m *Migrator) unlock(ctx context.Context) {
+	_ = m.storage().unlock(ctx, m.owner)
+}
+
+func (m *Migrator) storage() store {
+	if nil != m.store {
+		return m.store
+	}
+	return &mongoStore{records: m.db.Collection(m.collection), locks: m.db.Collection(m.lockCollection)}
+}
+
+// store 迁移记录与迁移锁的存储
+type store interface {
+	applied(ctx context.Context) (map[string]Record, error)
+	insert(ctx context.Context, record Record) error
+	delete(ctx context.Context, id string) error
+	// lock 锁不存在或在 now 时已过期时由 owner 持有到 expires，否则返回 ErrLocked
+	lock(ctx context.Context, owner string, now time.Time, expires time.Time) error
+	// renew owner 持有且在 now 时未过期的锁延长到 expires，否则返回 ErrLockLost
+	renew(ctx context.Context, owner string, now time.Time, expires time.Time) error
+	unlock(ctx context.Context, owner string) error
+}
+
+type mongoStore struct {
+	records *mongo.Collection
+	locks   *mongo.Collection
+}
+
+func (s *mongoStore) applied(ctx context.Context) (map[string]Record, error) {
+	cursor, err := s.records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	result := make(map[string]Record, len(records))
+	for _, r := range records {
+		result[r.ID] = r
+	}
+	return result, nil
+}
+
+func (s *mongoStore) insert(ctx context.Context, record Record) error {
+	_, err := s.records.InsertOne(ctx, record)
+	return err
+}
+
+func (s *mongoStore) delete(ctx context.Context, id string) error {
+	_, err := s.records.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// lock 锁已过期时更新锁文档，锁不存在时 upsert 插入，锁未过期时 upsert 的 _id 冲突
+func (s *mongoStore) lock(ctx context.Context, owner string, now time.Time, expires time.Time) error {
+	filter := bson.M{"_id": lockId, "expires_at": bson.M{"$lt": now}}
+	update := bson.M{"$set": bson.M{"owner": owner, "locked_at": now, "expires_at": expires}}
+	_, err := s.locks.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	return err
+}
+
+func (s *mongoStore) renew(ctx context.Context, owner string, now time.Time, expires time.Time) error {
+	filter := bson.M{"_id": lockId, "owner": owner, "expires_at": bson.M{"$gt": now}}
+	r, err := s.locks.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"expires_at": expires}})
+	if err != nil {
+		return err
+	}
+	if r.MatchedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (s *mongoStore) unlock(ctx context.Context, owner string) error {
+	_, err := s.locks.DeleteOne(ctx, bson.M{"_id": lockId, "owner": owner})
+	return err
+}
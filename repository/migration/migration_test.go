@@ -0,0 +1,296 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func newTestMigrator(t *testing.T) *Migrator {
+	m := NewMigrator(nil)
+	err := m.Register(
+		Migration{ID: "003_backfill", Up: noop},
+		Migration{ID: "001_init", Up: noop, Down: noop},
+		Migration{ID: "002_index", Up: noop, Down: noop},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func ids(migrations []Migration) []string {
+	result := make([]string, len(migrations))
+	for i, m := range migrations {
+		result[i] = m.ID
+	}
+	return result
+}
+
+func TestRegister(t *testing.T) {
+	m := newTestMigrator(t)
+	if err := m.Register(Migration{ID: "001_init", Up: noop}); err == nil {
+		t.Error("Register() duplicate id, want error")
+	}
+	if err := m.Register(Migration{ID: "004"}); err == nil {
+		t.Error("Register() without Up, want error")
+	}
+	if err := m.Register(Migration{Up: noop}); err == nil {
+		t.Error("Register() without id, want error")
+	}
+}
+
+func TestPending(t *testing.T) {
+	m := newTestMigrator(t)
+	applied := map[string]Record{"001_init": {ID: "001_init"}}
+
+	if got := ids(m.pending(applied, "")); len(got) != 2 || got[0] != "002_index" || got[1] != "003_backfill" {
+		t.Errorf("pending() = %v", got)
+	}
+	if got := ids(m.pending(applied, "002_index")); len(got) != 1 || got[0] != "002_index" {
+		t.Errorf("pending() to 002 = %v", got)
+	}
+	if _, err := m.Up(context.TODO(), "999", true); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("Up() unknown target error = %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	m := newTestMigrator(t)
+	applied := map[string]Record{"001_init": {ID: "001_init"}, "002_index": {ID: "002_index"}}
+
+	got, err := m.rollback(applied, 1)
+	if err != nil || len(got) != 1 || got[0].ID != "002_index" {
+		t.Errorf("rollback(1) = %v, %v", ids(got), err)
+	}
+	got, err = m.rollback(applied, 5)
+	if err != nil || len(got) != 2 || got[1].ID != "001_init" {
+		t.Errorf("rollback(5) = %v, %v", ids(got), err)
+	}
+
+	applied["003_backfill"] = Record{ID: "003_backfill"}
+	if _, err := m.rollback(applied, 1); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("rollback() irreversible error = %v", err)
+	}
+	applied["004_removed"] = Record{ID: "004_removed"}
+	if _, err := m.rollback(applied, 1); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("rollback() unknown error = %v", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	m := newTestMigrator(t)
+	at := time.Now()
+	status := m.status(map[string]Record{
+		"001_init":   {ID: "001_init", AppliedAt: at},
+		"000_legacy": {ID: "000_legacy", AppliedAt: at},
+	})
+	if len(status) != 4 {
+		t.Fatalf("status() = %v", status)
+	}
+	if s := status[0]; s.ID != "000_legacy" || !s.Applied || s.Registered {
+		t.Errorf("status[0] = %+v", s)
+	}
+	if s := status[1]; s.ID != "001_init" || !s.Applied || !s.Registered || !s.AppliedAt.Equal(at) {
+		t.Errorf("status[1] = %+v", s)
+	}
+	if s := status[3]; s.ID != "003_backfill" || s.Applied {
+		t.Errorf("status[3] = %+v", s)
+	}
+}
+
+// memoryStore 内存中的迁移记录与锁
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	owner     string
+	expiresAt time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: map[string]Record{}}
+}
+
+func (s *memoryStore) applied(context.Context) (map[string]Record, error) {
+	result := make(map[string]Record, len(s.records))
+	for id, r := range s.records {
+		result[id] = r
+	}
+	return result, nil
+}
+
+func (s *memoryStore) insert(_ context.Context, record Record) error {
+	s.records[record.ID] = record
+	return nil
+}
+
+func (s *memoryStore) delete(_ context.Context, id string) error {
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryStore) lock(_ context.Context, owner string, now time.Time, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if "" != s.owner && !s.expiresAt.Before(now) {
+		return ErrLocked
+	}
+	s.owner, s.expiresAt = owner, expires
+	return nil
+}
+
+func (s *memoryStore) renew(_ context.Context, owner string, now time.Time, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner != owner || !s.expiresAt.After(now) {
+		return ErrLockLost
+	}
+	s.expiresAt = expires
+	return nil
+}
+
+// steal 模拟其他进程在锁过期后获取锁
+func (s *memoryStore) steal(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.owner, s.expiresAt = owner, time.Now().Add(time.Hour)
+}
+
+func (s *memoryStore) unlock(_ context.Context, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.owner == owner {
+		s.owner = ""
+	}
+	return nil
+}
+
+func TestDownInvalidSteps(t *testing.T) {
+	m := newTestMigrator(t)
+	m.store = newMemoryStore()
+	if _, err := m.Down(context.TODO(), -1, false); err == nil {
+		t.Error("Down(-1) error = nil")
+	}
+}
+
+func TestLock(t *testing.T) {
+	store := newMemoryStore()
+	now := time.Now()
+	clock := func() time.Time { return now }
+	holder := newTestMigrator(t).SetOwner("a").SetLockTTL(time.Minute)
+	holder.store, holder.clock = store, clock
+	other := newTestMigrator(t).SetOwner("b")
+	other.store, other.clock = store, clock
+
+	if err := holder.lock(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := other.Up(context.TODO(), "", false); !errors.Is(err, ErrLocked) || len(done) != 0 {
+		t.Fatalf("Up() while locked = %v, %v", done, err)
+	}
+	if len(store.records) != 0 || "a" != store.owner {
+		t.Fatalf("Up() while locked changed store: %+v", store)
+	}
+
+	// 持有锁的进程退出后，锁过期时其他进程接管
+	now = now.Add(time.Minute + time.Second)
+	done, err := other.Up(context.TODO(), "", false)
+	if err != nil || len(done) != 3 {
+		t.Fatalf("Up() after lock expired = %v, %v", done, err)
+	}
+	if "" != store.owner {
+		t.Errorf("lock owner after Up() = %s", store.owner)
+	}
+}
+
+func TestUpStopsOnFailure(t *testing.T) {
+	store := newMemoryStore()
+	m := NewMigrator(nil).SetOwner("a")
+	m.store = store
+	boom := errors.New("boom")
+	ran := false
+	m.MustRegister(
+		Migration{ID: "001", Up: noop},
+		Migration{ID: "002", Up: func(context.Context, *mongo.Database) error { return boom }},
+		Migration{ID: "003", Up: func(context.Context, *mongo.Database) error { ran = true; return nil }},
+	)
+
+	done, err := m.Up(context.TODO(), "", false)
+	if !errors.Is(err, boom) || len(done) != 1 || done[0] != "001" {
+		t.Fatalf("Up() = %v, %v", done, err)
+	}
+	if ran {
+		t.Error("migration after the failed one was executed")
+	}
+	if _, ok := store.records["002"]; ok || len(store.records) != 1 {
+		t.Errorf("records = %v", store.records)
+	}
+	if "" != store.owner {
+		t.Errorf("lock not released after failure, owner = %s", store.owner)
+	}
+}
+
+func TestUpAbortsWhenLockLost(t *testing.T) {
+	store := newMemoryStore()
+	m := NewMigrator(nil).SetOwner("a")
+	m.store = store
+	ran := false
+	m.MustRegister(
+		Migration{ID: "001", Up: func(context.Context, *mongo.Database) error { store.steal("b"); return nil }},
+		Migration{ID: "002", Up: func(context.Context, *mongo.Database) error { ran = true; return nil }},
+	)
+
+	done, err := m.Up(context.TODO(), "", false)
+	if !errors.Is(err, ErrLockLost) || len(done) != 1 {
+		t.Fatalf("Up() = %v, %v", done, err)
+	}
+	if ran {
+		t.Error("migration executed after the lock was lost")
+	}
+	if "b" != store.owner {
+		t.Errorf("lock owner = %s, want b", store.owner)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	store := newMemoryStore()
+	m := NewMigrator(nil).SetOwner("a").SetLockTTL(60 * time.Millisecond)
+	m.store = store
+	other := NewMigrator(nil).SetOwner("b")
+	other.store = store
+	var otherErr error
+	m.MustRegister(Migration{ID: "001", Up: func(ctx context.Context, _ *mongo.Database) error {
+		// 执行时间超过 lockTTL，续期使锁不会过期
+		time.Sleep(200 * time.Millisecond)
+		otherErr = other.lock(ctx)
+		return nil
+	}})
+	if _, err := m.Up(context.TODO(), "", false); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(otherErr, ErrLocked) {
+		t.Errorf("lock() during a long migration error = %v, want ErrLocked", otherErr)
+	}
+
+	// 续期时发现锁已被其他进程获取，取消正在执行的迁移
+	m = NewMigrator(nil).SetOwner("a").SetLockTTL(30 * time.Millisecond)
+	m.store = store
+	m.MustRegister(Migration{ID: "002", Up: func(ctx context.Context, _ *mongo.Database) error {
+		store.steal("b")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	}})
+	if _, err := m.Up(context.TODO(), "", false); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Up() error = %v, want ErrLockLost", err)
+	}
+}
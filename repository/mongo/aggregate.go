@@ -0,0 +1,249 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	sort2 "github.com/aomi-go/data/common/sort"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Accumulator $group、$bucket 的累加字段
+type Accumulator struct {
+	Field    string
+	Operator string
+	Expr     interface{}
+}
+
+// AccSum 求和，expr 为字段引用（如 "$amount"）或常量
+func AccSum(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$sum", Expr: expr}
+}
+
+// AccCount 计数
+func AccCount(field string) Accumulator {
+	return AccSum(field, 1)
+}
+
+// AccAvg 平均值
+func AccAvg(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$avg", Expr: expr}
+}
+
+// AccMin 最小值
+func AccMin(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$min", Expr: expr}
+}
+
+// AccMax 最大值
+func AccMax(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$max", Expr: expr}
+}
+
+// AccFirst 分组中的第一个值
+func AccFirst(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$first", Expr: expr}
+}
+
+// AccLast 分组中的最后一个值
+func AccLast(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$last", Expr: expr}
+}
+
+// AccPush 收集为数组
+func AccPush(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$push", Expr: expr}
+}
+
+// AccAddToSet 收集为去重数组
+func AccAddToSet(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: "$addToSet", Expr: expr}
+}
+
+func accumulate(doc bson.D, accumulators []Accumulator) bson.D {
+	for _, a := range accumulators {
+		doc = append(doc, bson.E{Key: a.Field, Value: bson.D{{Key: a.Operator, Value: a.Expr}}})
+	}
+	return doc
+}
+
+// AggregateBuilder 聚合管道构建器
+type AggregateBuilder struct {
+	pipeline mongo.Pipeline
+}
+
+func NewAggregateBuilder() *AggregateBuilder {
+	return &AggregateBuilder{pipeline: mongo.Pipeline{}}
+}
+
+// Stage 追加任意阶段
+func (b *AggregateBuilder) Stage(stage bson.D) *AggregateBuilder {
+	b.pipeline = append(b.pipeline, stage)
+	return b
+}
+
+// Match 构建 $match 阶段，filter 可以为 *QueryBuilder
+func (b *AggregateBuilder) Match(filter interface{}) *AggregateBuilder {
+	if qb, ok := filter.(*QueryBuilder); ok {
+		filter = qb.Build()
+	}
+	return b.Stage(bson.D{{Key: "$match", Value: filter}})
+}
+
+// Group 构建 $group 阶段，id 为分组表达式（如 "$category"，nil 表示全部数据为一组）
+func (b *AggregateBuilder) Group(id interface{}, accumulators ...Accumulator) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$group", Value: accumulate(bson.D{{Key: "_id", Value: id}}, accumulators)}})
+}
+
+// Project 构建 $project 阶段
+func (b *AggregateBuilder) Project(projection interface{}) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$project", Value: projection}})
+}
+
+// Sort 构建 $sort 阶段，排序规则与 GetSortOpts 一致，没有排序字段时忽略
+func (b *AggregateBuilder) Sort(s sort2.Sort) *AggregateBuilder {
+	keys := GetSortOpts(s).Sort.(bson.D)
+	if len(keys) == 0 {
+		return b
+	}
+	return b.Stage(bson.D{{Key: "$sort", Value: keys}})
+}
+
+// Lookup 构建 $lookup 阶段
+func (b *AggregateBuilder) Lookup(from string, localField string, foreignField string, as string) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: foreignField},
+		{Key: "as", Value: as},
+	}}})
+}
+
+// Unwind 构建 $unwind 阶段，path 为字段引用（如 "$items"）
+func (b *AggregateBuilder) Unwind(path string, preserveNullAndEmptyArrays bool) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$unwind", Value: bson.D{
+		{Key: "path", Value: path},
+		{Key: "preserveNullAndEmptyArrays", Value: preserveNullAndEmptyArrays},
+	}}})
+}
+
+// Facet 构建 $facet 阶段，每个子管道的结果输出到同名字段
+func (b *AggregateBuilder) Facet(facets map[string]*AggregateBuilder) *AggregateBuilder {
+	doc := bson.M{}
+	for name, facet := range facets {
+		doc[name] = facet.Build()
+	}
+	return b.Stage(bson.D{{Key: "$facet", Value: doc}})
+}
+
+// Bucket 构建 $bucket 阶段，defaultBucket 为 nil 时不设置 default，没有 output 时只统计数量
+func (b *AggregateBuilder) Bucket(groupBy interface{}, boundaries []interface{}, defaultBucket interface{}, output ...Accumulator) *AggregateBuilder {
+	doc := bson.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "boundaries", Value: boundaries},
+	}
+	if nil != defaultBucket {
+		doc = append(doc, bson.E{Key: "default", Value: defaultBucket})
+	}
+	if len(output) > 0 {
+		doc = append(doc, bson.E{Key: "output", Value: accumulate(bson.D{}, output)})
+	}
+	return b.Stage(bson.D{{Key: "$bucket", Value: doc}})
+}
+
+// Skip 构建 $skip 阶段
+func (b *AggregateBuilder) Skip(n int64) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$skip", Value: n}})
+}
+
+// Limit 构建 $limit 阶段
+func (b *AggregateBuilder) Limit(n int64) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$limit", Value: n}})
+}
+
+// Count 构建 $count 阶段，数量输出到 field 字段
+func (b *AggregateBuilder) Count(field string) *AggregateBuilder {
+	return b.Stage(bson.D{{Key: "$count", Value: field}})
+}
+
+func (b *AggregateBuilder) Build() mongo.Pipeline {
+	return b.pipeline
+}
+
+// pipelineOf 将 *AggregateBuilder、mongo.Pipeline、[]bson.D、bson.A 等转换为阶段列表
+func pipelineOf(pipeline interface{}) (bson.A, error) {
+	if b, ok := pipeline.(*AggregateBuilder); ok {
+		pipeline = b.Build()
+	}
+	if nil == pipeline {
+		return bson.A{}, nil
+	}
+	v := reflect.ValueOf(pipeline)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("mongo: invalid pipeline %T", pipeline)
+	}
+	stages := make(bson.A, v.Len())
+	for i := range stages {
+		stages[i] = v.Index(i).Interface()
+	}
+	return stages, nil
+}
+
+// Aggregate 执行聚合并将结果解码到 results（切片指针）。
+// 管道前会追加租户与软删除的 $match 条件，$lookup 关联的集合不受限定
+func (d *DocumentRepository[Entity]) Aggregate(ctx context.Context, pipeline interface{}, results interface{}, opts ...*options.AggregateOptions) (err error) {
+	ctx, done := d.observe(ctx, OpAggregate)
+	defer func() {
+		var n int64
+		if v := reflect.ValueOf(results); nil == err && v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+			n = int64(v.Elem().Len())
+		}
+		done(n, err)
+	}()
+
+	cursor, err := d.aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+func (d *DocumentRepository[Entity]) aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	stages, err := d.scopedPipeline(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Aggregate(ctx, stages, opts...)
+}
+
+// scopedPipeline 在管道前追加仓储级别的限定条件
+func (d *DocumentRepository[Entity]) scopedPipeline(ctx context.Context, pipeline interface{}) (bson.A, error) {
+	stages, err := pipelineOf(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := d.scope(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if isEmptyFilter(scoped) {
+		return stages, nil
+	}
+	return append(bson.A{bson.D{{Key: "$match", Value: scoped}}}, stages...), nil
+}
+
+// AggregateAs 执行聚合并将结果解码为 Out
+func AggregateAs[Entity interface{}, Out interface{}](ctx context.Context, repo *DocumentRepository[Entity], pipeline interface{}, opts ...*options.AggregateOptions) ([]*Out, error) {
+	results := make([]*Out, 0)
+	if err := repo.Aggregate(ctx, pipeline, &results, opts...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
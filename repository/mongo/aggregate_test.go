@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/common/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAggregateBuilder(t *testing.T) {
+	pipeline := NewAggregateBuilder().
+		Match(NewQueryBuilder().Is("status", "paid")).
+		Lookup("users", "user_id", "_id", "user").
+		Unwind("$user", true).
+		Group("$user.name", AccSum("total", "$amount"), AccCount("orders")).
+		Sort(sort.NewSortBy(sort.DESC, "total")).
+		Skip(10).
+		Limit(5).
+		Build()
+
+	want := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": "paid"}}},
+		{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "users"}, {Key: "localField", Value: "user_id"}, {Key: "foreignField", Value: "_id"}, {Key: "as", Value: "user"}}}},
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$user"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$user.name"},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			{Key: "orders", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "total", Value: -1}}}},
+		{{Key: "$skip", Value: int64(10)}},
+		{{Key: "$limit", Value: int64(5)}},
+	}
+	if !reflect.DeepEqual(pipeline, want) {
+		t.Errorf("Build() = %v\nwant %v", pipeline, want)
+	}
+
+	facet := NewAggregateBuilder().Facet(map[string]*AggregateBuilder{
+		"total":   NewAggregateBuilder().Count("n"),
+		"buckets": NewAggregateBuilder().Bucket("$amount", []interface{}{0, 100}, "other"),
+	}).Build()
+	doc := facet[0][0].Value.(bson.M)
+	if !reflect.DeepEqual(doc["total"], mongo.Pipeline{{{Key: "$count", Value: "n"}}}) {
+		t.Errorf("Facet() total = %v", doc["total"])
+	}
+	bucket := doc["buckets"].(mongo.Pipeline)[0][0].Value.(bson.D)
+	if len(bucket) != 3 || bucket[2].Key != "default" {
+		t.Errorf("Bucket() = %v", bucket)
+	}
+}
+
+func TestScopedPipeline(t *testing.T) {
+	stages := mongo.Pipeline{{{Key: "$limit", Value: 1}}}
+
+	got, err := (&DocumentRepository[User]{}).scopedPipeline(context.TODO(), stages)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("scopedPipeline() = %v, %v", got, err)
+	}
+
+	repo := (&DocumentRepository[tenantUser]{}).SetMultiTenant(true)
+	got, err = repo.scopedPipeline(tenant.WithTenant(context.TODO(), "t1"), NewAggregateBuilder().Limit(1))
+	if err != nil || len(got) != 2 {
+		t.Fatalf("scopedPipeline() = %v, %v", got, err)
+	}
+	if match := got[0].(bson.D)[0]; match.Key != "$match" || !reflect.DeepEqual(match.Value, bson.M{TenantIdField: "t1"}) {
+		t.Errorf("scopedPipeline() first stage = %v", match)
+	}
+
+	if _, err := repo.scopedPipeline(context.TODO(), stages); err == nil {
+		t.Errorf("scopedPipeline() without tenant error = nil")
+	}
+	if _, err := pipelineOf(bson.M{}); err == nil {
+		t.Errorf("pipelineOf(bson.M) error = nil")
+	}
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aomi-go/data/common/auditor"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+)
+
+// Clock 返回当前时间，测试时可替换为固定时钟
+type Clock func() time.Time
+
+// AuditorResolver 从 context 中解析当前操作人
+type AuditorResolver func(ctx context.Context) (string, bool)
+
+// SetClock 设置审计字段使用的时钟
+func (d *DocumentRepository[Entity]) SetClock(clock Clock) *DocumentRepository[Entity] {
+	d.clock = clock
+	return d
+}
+
+// SetAuditorResolver 设置操作人解析器，默认使用 auditor.FromContext
+func (d *DocumentRepository[Entity]) SetAuditorResolver(resolver AuditorResolver) *DocumentRepository[Entity] {
+	d.auditorResolver = resolver
+	return d
+}
+
+// now 返回截断到毫秒的 UTC 时间，与 MongoDB 的日期精度保持一致
+func (d *DocumentRepository[Entity]) now() time.Time {
+	clock := d.clock
+	if nil == clock {
+		clock = time.Now
+	}
+	return clock().UTC().Truncate(time.Millisecond)
+}
+
+// audit 填充审计字段：创建时间/创建人为空时写入，更新时间/更新人每次写入
+func (d *DocumentRepository[Entity]) audit(ctx context.Context, entity *Entity) {
+	var e interface{} = entity
+	a, ok := e.(mongoxentity.TimeAuditable)
+	if !ok {
+		return
+	}
+	now := d.now()
+	if a.GetCreatedAt().IsZero() {
+		a.SetCreatedAt(now)
+	}
+	a.SetUpdatedAt(now)
+
+	full, ok := e.(mongoxentity.Auditable)
+	if !ok {
+		return
+	}
+	resolver := d.auditorResolver
+	if nil == resolver {
+		resolver = auditor.FromContext
+	}
+	if by, ok := resolver(ctx); ok {
+		if "" == full.GetCreatedBy() {
+			full.SetCreatedBy(by)
+		}
+		full.SetUpdatedBy(by)
+	}
+}
@@ -0,0 +1,36 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/common/auditor"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+)
+
+type auditedUser struct {
+	mongoxentity.AuditFields `bson:",inline"`
+	ID                       mongoxentity.StrObjectId `bson:"_id,omitempty"`
+}
+
+func TestAudit(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := created
+	repo := (&DocumentRepository[auditedUser]{}).SetClock(func() time.Time { return now })
+
+	u := &auditedUser{}
+	repo.audit(auditor.WithAuditor(context.TODO(), "alice"), u)
+	if !u.CreatedAt.Equal(created) || !u.UpdatedAt.Equal(created) || u.CreatedBy != "alice" || u.UpdatedBy != "alice" {
+		t.Fatalf("audit() on insert = %+v", u.AuditFields)
+	}
+
+	now = created.Add(time.Hour + time.Microsecond)
+	repo.audit(auditor.WithAuditor(context.TODO(), "bob"), u)
+	if !u.CreatedAt.Equal(created) || u.CreatedBy != "alice" {
+		t.Errorf("audit() changed created fields: %+v", u.AuditFields)
+	}
+	if !u.UpdatedAt.Equal(created.Add(time.Hour)) || u.UpdatedBy != "bob" {
+		t.Errorf("audit() on update = %+v", u.AuditFields)
+	}
+}
@@ -87,6 +87,15 @@ func (b *QueryBuilder) In(field string, values ...interface{}) *QueryBuilder {
 	return b
 }
 
+// Values 将类型化的切片转换为 In、NotIn 的参数，如 b.In("status", mongo.Values(statuses)...)
+func Values[T interface{}](values []T) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
 func (b *QueryBuilder) NotIn(field string, values ...interface{}) *QueryBuilder {
 	b.filter[field] = bson.M{"$nin": values}
 	return b
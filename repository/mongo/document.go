@@ -5,10 +5,13 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 
+	"github.com/aomi-go/data/common"
 	"github.com/aomi-go/data/common/page"
 	"github.com/aomi-go/data/common/sort"
 	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/aomi-go/data/repository/instrument"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -27,6 +30,7 @@ func NewDocumentRepository[E interface{}](db *mongo.Database, collectionName str
 		db:             db,
 		collection:     db.Collection(collectionName, collectionOpts...),
 		collectionName: collectionName,
+		collectionOpts: collectionOpts,
 		IDFieldName:    "ID",
 	}
 }
@@ -35,10 +39,45 @@ type DocumentRepository[Entity interface{}] struct {
 	db             *mongo.Database
 	collection     *mongo.Collection
 	collectionName string
+	collectionOpts []*options.CollectionOptions
 	IDFieldName    string
+
+	clock           Clock
+	auditorResolver AuditorResolver
+	softDelete      bool
+	multiTenant     bool
+	resolver        CollectionResolver
+	collections     *sync.Map
+	cursorCodec     *page.CursorCodec
+	instrumentation *instrument.Instrumentation
+}
+
+func (d *DocumentRepository[Entity]) Save(ctx context.Context, entity *Entity) (saved *Entity, err error) {
+	ctx, done := d.observe(ctx, OpSave)
+	defer func() { done(countOf(saved), err) }()
+
+	if err := d.stampTenant(ctx, entity); err != nil {
+		return nil, err
+	}
+	d.audit(ctx, entity)
+	if err := beforeSave(ctx, entity); err != nil {
+		return nil, err
+	}
+	if _, err := d.save(ctx, entity); err != nil {
+		return nil, err
+	}
+	if err := afterSave(ctx, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
 }
 
-func (d *DocumentRepository[Entity]) Save(ctx context.Context, entity *Entity) (*Entity, error) {
+// save 按 ID 是否存在执行替换或插入
+func (d *DocumentRepository[Entity]) save(ctx context.Context, entity *Entity) (*Entity, error) {
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	idFieldValue, idFieldOk := d.getIdFieldValue(entity)
 	idOk := false
@@ -46,19 +85,35 @@ func (d *DocumentRepository[Entity]) Save(ctx context.Context, entity *Entity) (
 	if idFieldOk {
 		id, idOk = d.ToObjectIdWithCheck(idFieldValue.Interface())
 	}
+	version, versioned := versionFieldOf(entity)
 
 	if idOk && !id.IsZero() {
-		filter := bson.M{"_id": id}
+		if versioned {
+			return d.saveWithVersion(ctx, collection, id, entity, version)
+		}
+		filter := d.saveFilter(ctx, id)
 		opts := options.Replace().SetUpsert(true) // This option will create a new document if no document matches the filter
 
-		_, err := d.collection.ReplaceOne(ctx, filter, entity, opts)
+		_, err := collection.ReplaceOne(ctx, filter, entity, opts)
 		if err != nil {
+			if isIdDuplicateKeyError(err) {
+				if d.hasDeleted(ctx, collection, []primitive.ObjectID{id}) {
+					return nil, ErrSoftDeleted
+				}
+				// 同 ID 的文档属于其他租户
+				if _, scoped, _ := d.tenantOf(ctx); scoped {
+					return nil, common.ErrCrossTenant
+				}
+			}
 			return nil, err
 		}
 		return entity, nil
 	}
 	d.setIdFieldValue(idFieldValue, primitive.NewObjectID())
-	_, err := d.collection.InsertOne(ctx, entity)
+	if versioned {
+		version.set(entity, version.get(entity)+1)
+	}
+	_, err = collection.InsertOne(ctx, entity)
 	return entity, err
 }
 
@@ -89,49 +144,130 @@ func (d *DocumentRepository[Entity]) FindAllById(ctx context.Context, ids ...int
 	return d.Find(ctx, filter)
 }
 
-func (d *DocumentRepository[Entity]) FindById(ctx context.Context, id interface{}) (*Entity, error) {
+func (d *DocumentRepository[Entity]) FindById(ctx context.Context, id interface{}) (found *Entity, err error) {
+	ctx, done := d.observe(ctx, OpFindById)
+	defer func() { done(countOf(found), err) }()
+
+	filter, err := d.scope(ctx, map[string]interface{}{"_id": d.ToObjectId(id)})
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var result Entity
-	err := d.collection.FindOne(ctx, map[string]interface{}{"_id": d.ToObjectId(id)}).Decode(&result)
+	err = collection.FindOne(ctx, filter).Decode(&result)
 	if err := toErr(err); nil != err {
 		return nil, err
 	}
+	if err := afterLoad(ctx, &result); err != nil {
+		return nil, err
+	}
 	return &result, err
 }
 
 func (d *DocumentRepository[Entity]) ExistsById(ctx context.Context, id interface{}) (bool, error) {
 	return d.Exist(ctx, map[string]interface{}{"_id": d.ToObjectId(id)})
 }
-func (d *DocumentRepository[Entity]) DeleteById(ctx context.Context, id interface{}) (bool, error) {
-	r, err := d.collection.DeleteOne(ctx, map[string]interface{}{"_id": d.ToObjectId(id)})
+func (d *DocumentRepository[Entity]) DeleteById(ctx context.Context, id interface{}) (deleted bool, err error) {
+	ctx, done := d.observe(ctx, OpDeleteById)
+	defer func() {
+		var n int64
+		if deleted {
+			n = 1
+		}
+		done(n, err)
+	}()
+
+	filter := map[string]interface{}{"_id": d.ToObjectId(id)}
+	if err := d.beforeDelete(ctx, filter); err != nil {
+		return false, err
+	}
+	scoped, err := d.scope(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return false, err
+	}
+	if d.softDelete {
+		r, err := collection.UpdateOne(ctx, scoped, d.softDeleteUpdate(ctx))
+		if nil != err {
+			return false, err
+		}
+		return r.ModifiedCount > 0, nil
+	}
+	r, err := collection.DeleteOne(ctx, scoped)
 	if nil == err {
 		return r.DeletedCount > 0, nil
 	}
 	return false, err
 }
 
-func (d *DocumentRepository[Entity]) SaveMany(ctx context.Context, entities []*Entity) ([]*Entity, error) {
+func (d *DocumentRepository[Entity]) SaveMany(ctx context.Context, entities []*Entity) (saved []*Entity, err error) {
+	ctx, done := d.observe(ctx, OpSaveMany)
+	defer func() { done(int64(len(saved)), err) }()
+
 	var models []mongo.WriteModel
+	// 带版本号的替换操作数量，用于检测乐观锁冲突
+	var versionedReplaces int64
+	// 按 ID 替换的文档，用于判断冲突是否由已软删除的文档引起
+	var replacedIds []primitive.ObjectID
+	// 保存失败时恢复分配的 ID 与递增的版本号
+	var restores []func()
+	defer func() {
+		if err != nil {
+			for _, restore := range restores {
+				restore()
+			}
+		}
+	}()
 
 	for _, entity := range entities {
+		if err := d.stampTenant(ctx, entity); err != nil {
+			return nil, err
+		}
+		d.audit(ctx, entity)
+		if err := beforeSave(ctx, entity); err != nil {
+			return nil, err
+		}
 		idFieldValue, idFieldOk := d.getIdFieldValue(entity)
 		idOk := false
 		var id primitive.ObjectID
 		if idFieldOk {
 			id, idOk = d.ToObjectIdWithCheck(idFieldValue.Interface())
 		}
+		version, versioned := versionFieldOf(entity)
 
 		if idOk {
 			// 如果实体的 ID 不为空，则表示这是一个现有实体，需要更新
-			filter := bson.M{"_id": id}
+			filter := d.saveFilter(ctx, id)
+			replacedIds = append(replacedIds, id)
+			upsert := true
+			if versioned {
+				expected := version.guard(entity, filter)
+				restores = append(restores, func() { version.set(entity, expected) })
+				upsert = expected == 0
+				versionedReplaces++
+			}
 			model := mongo.NewReplaceOneModel().
 				SetFilter(filter).
 				SetReplacement(entity).
-				SetUpsert(true)
+				SetUpsert(upsert)
 			models = append(models, model)
 		} else {
 			// 如果实体的 ID 为空，则表示这是一个新实体，需要插入
 			if idFieldOk {
+				original := reflect.ValueOf(idFieldValue.Interface())
 				d.setIdFieldValue(idFieldValue, primitive.NewObjectID())
+				restores = append(restores, func() { idFieldValue.Set(original) })
+			}
+			if versioned {
+				expected := version.get(entity)
+				version.set(entity, expected+1)
+				restores = append(restores, func() { version.set(entity, expected) })
 			}
 			model := mongo.NewInsertOneModel().SetDocument(entity)
 			models = append(models, model)
@@ -139,17 +275,57 @@ func (d *DocumentRepository[Entity]) SaveMany(ctx context.Context, entities []*E
 	}
 
 	// 执行批量写操作
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	opts := options.BulkWrite()
-	_, err := d.collection.BulkWrite(ctx, models, opts)
+	r, err := collection.BulkWrite(ctx, models, opts)
+	if versionedReplaces > 0 {
+		var inserted, matched, upserted int64
+		if nil != r {
+			inserted, matched, upserted = r.InsertedCount, r.MatchedCount, r.UpsertedCount
+		}
+		if versionConflict(err, int64(len(models))-inserted, matched, upserted) {
+			if d.hasDeleted(ctx, collection, replacedIds) {
+				return nil, ErrSoftDeleted
+			}
+			return nil, common.ErrOptimisticLock
+		}
+	}
 	if err != nil {
+		if isIdDuplicateKeyError(err) {
+			if d.hasDeleted(ctx, collection, replacedIds) {
+				return nil, ErrSoftDeleted
+			}
+			if _, scoped, _ := d.tenantOf(ctx); scoped {
+				return nil, common.ErrCrossTenant
+			}
+		}
 		return nil, err
 	}
 
+	for _, entity := range entities {
+		if err := afterSave(ctx, entity); err != nil {
+			return nil, err
+		}
+	}
 	return entities, nil
 }
 
-func (d *DocumentRepository[Entity]) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]*Entity, error) {
-	cursor, err := d.collection.Find(ctx, filter, opts...)
+func (d *DocumentRepository[Entity]) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (found []*Entity, err error) {
+	ctx, done := d.observe(ctx, OpFind)
+	defer func() { done(int64(len(found)), err) }()
+
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx, filter, opts...)
 	if err := toErr(err); nil != err {
 		return nil, err
 	}
@@ -162,6 +338,9 @@ func (d *DocumentRepository[Entity]) Find(ctx context.Context, filter interface{
 		if nil != err {
 			return nil, err
 		}
+		if err := afterLoad(ctx, &item); err != nil {
+			return nil, err
+		}
 		result = append(result, &item)
 	}
 	if err := cursor.Err(); err != nil {
@@ -170,26 +349,68 @@ func (d *DocumentRepository[Entity]) Find(ctx context.Context, filter interface{
 	return result, nil
 }
 
-func (d *DocumentRepository[Entity]) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) (*Entity, error) {
+func (d *DocumentRepository[Entity]) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) (found *Entity, err error) {
+	ctx, done := d.observe(ctx, OpFindOne)
+	defer func() { done(countOf(found), err) }()
+
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var result Entity
-	err := d.collection.FindOne(ctx, filter, opts...).Decode(&result)
+	err = collection.FindOne(ctx, filter, opts...).Decode(&result)
 	if err := toErr(err); nil != err {
 		return nil, err
 	}
+	if err := afterLoad(ctx, &result); err != nil {
+		return nil, err
+	}
 	return &result, err
 }
 
-func (d *DocumentRepository[Entity]) FindOneAndModify(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (*Entity, error) {
+func (d *DocumentRepository[Entity]) FindOneAndModify(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (found *Entity, err error) {
+	ctx, done := d.observe(ctx, OpFindOneAndModify)
+	defer func() { done(countOf(found), err) }()
+
+	if err := d.tenantUpdateGuard(ctx, update); err != nil {
+		return nil, err
+	}
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	var result Entity
-	err := d.collection.FindOneAndUpdate(ctx, filter, update, opts...).Decode(&result)
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts...).Decode(&result)
 	if err := toErr(err); nil != err {
 		return nil, err
 	}
+	if err := afterLoad(ctx, &result); err != nil {
+		return nil, err
+	}
 	return &result, err
 }
 
-func (d *DocumentRepository[Entity]) Count(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
-	return d.collection.CountDocuments(ctx, filter, opts...)
+func (d *DocumentRepository[Entity]) Count(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (count int64, err error) {
+	ctx, done := d.observe(ctx, OpCount)
+	defer func() { done(0, err) }()
+
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, filter, opts...)
 }
 
 func (d *DocumentRepository[Entity]) Exist(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (bool, error) {
@@ -200,8 +421,30 @@ func (d *DocumentRepository[Entity]) Exist(ctx context.Context, filter interface
 	}
 }
 
-func (d *DocumentRepository[Entity]) Delete(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
-	r, e := d.collection.DeleteMany(ctx, filter, opts...)
+// Delete 删除满足条件的数据，软删除模式下以更新写入删除标记，opts 中的 collation、hint 等同样生效
+func (d *DocumentRepository[Entity]) Delete(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (deleted int64, err error) {
+	ctx, done := d.observe(ctx, OpDelete)
+	defer func() { done(deleted, err) }()
+
+	if err := d.beforeDelete(ctx, filter); err != nil {
+		return 0, err
+	}
+	scoped, err := d.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if d.softDelete {
+		r, err := collection.UpdateMany(ctx, scoped, d.softDeleteUpdate(ctx), softDeleteOptions(opts))
+		if nil != err {
+			return 0, err
+		}
+		return r.ModifiedCount, nil
+	}
+	r, e := collection.DeleteMany(ctx, scoped, opts...)
 	if nil != e {
 		return 0, e
 	}
@@ -233,6 +476,27 @@ func (d *DocumentRepository[Entity]) QueryWithPage(ctx context.Context, filter i
 	return page.NewPage[Entity](entities, total, pageable), nil
 }
 
+// QueryWithSlice 分片查询，多查询一条数据判断是否还有下一页，不统计总数
+func (d *DocumentRepository[Entity]) QueryWithSlice(ctx context.Context, filter interface{}, pageable *page.Pageable) (*page.Slice[Entity], error) {
+	if nil == pageable {
+		pageable = page.NewDefaultPageable()
+	}
+
+	pageOpts := options.Find().SetSkip(pageable.GetOffset()).SetLimit(int64(pageable.GetSize()) + 1)
+	sortOpts := GetSortOpts(pageable.Sort)
+
+	entities, err := d.Find(ctx, filter, pageOpts, sortOpts)
+	if nil != err {
+		return nil, err
+	}
+
+	hasNext := len(entities) > pageable.GetSize()
+	if hasNext {
+		entities = entities[:pageable.GetSize()]
+	}
+	return page.NewSlice[Entity](entities, hasNext, pageable), nil
+}
+
 // QueryWithSort 排序查询
 func (d *DocumentRepository[Entity]) QueryWithSort(ctx context.Context, filter interface{}, sort *sort.Sort) ([]*Entity, error) {
 	var opts *options.FindOptions
@@ -241,24 +505,63 @@ func (d *DocumentRepository[Entity]) QueryWithSort(ctx context.Context, filter i
 	}
 	return d.Find(ctx, filter, opts)
 }
-func (d *DocumentRepository[Entity]) FindWithCursor(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
-	cursor, err := d.collection.Find(ctx, filter, opts...)
+func (d *DocumentRepository[Entity]) FindWithCursor(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (cursor *mongo.Cursor, err error) {
+	ctx, done := d.observe(ctx, OpFindWithCursor)
+	defer func() { done(0, err) }()
+
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err = collection.Find(ctx, filter, opts...)
 	if err := toErr(err); nil != err {
 		return nil, err
 	}
 	return cursor, nil
 }
 
-func (d *DocumentRepository[Entity]) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (int64, error) {
-	r, err := d.collection.UpdateMany(ctx, filter, update, opts...)
+func (d *DocumentRepository[Entity]) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (modified int64, err error) {
+	ctx, done := d.observe(ctx, OpUpdateOne)
+	defer func() { done(modified, err) }()
+
+	if err := d.tenantUpdateGuard(ctx, update); err != nil {
+		return 0, err
+	}
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r, err := collection.UpdateMany(ctx, filter, update, opts...)
 	if nil != err {
 		return 0, err
 	}
 	return r.ModifiedCount, nil
 }
 func (d *DocumentRepository[Entity]) UpdateMany(ctx context.Context, filter interface{}, update interface{},
-	opts ...*options.UpdateOptions) (int64, error) {
-	r, err := d.collection.UpdateMany(ctx, filter, update, opts...)
+	opts ...*options.UpdateOptions) (modified int64, err error) {
+	ctx, done := d.observe(ctx, OpUpdateMany)
+	defer func() { done(modified, err) }()
+
+	if err := d.tenantUpdateGuard(ctx, update); err != nil {
+		return 0, err
+	}
+	filter, err = d.scope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r, err := collection.UpdateMany(ctx, filter, update, opts...)
 	if nil != err {
 		return 0, err
 	}
@@ -274,36 +577,48 @@ func (d *DocumentRepository[Entity]) convertEntitiesToInterface(entities []*Enti
 }
 
 func (d *DocumentRepository[Entity]) getIdFieldValue(doc *Entity) (reflect.Value, bool) {
-	v := reflect.ValueOf(doc)
-	if v.Kind() != reflect.Ptr || v.IsNil() {
-		return reflect.Value{}, false
-	}
-	elem := v.Elem()
-	if elem.Kind() != reflect.Struct {
-		return reflect.Value{}, false
-	}
-
-	// 查找 ID 字段
-	idField := elem.FieldByName("ID")
-	//if !idField.IsValid() || idField.Type() != reflect.TypeOf(primitive.ObjectID{}) {
-	if !idField.IsValid() {
-		return reflect.Value{}, false
-	}
-	return idField, true
+	return GetIdFieldValue(doc, "ID")
 }
 
 func (d *DocumentRepository[Entity]) setIdFieldValue(idField reflect.Value, id primitive.ObjectID) {
-	if idField.Type() == reflect.TypeOf(primitive.ObjectID{}) {
-		idField.Set(reflect.ValueOf(id))
-	} else if idField.Kind() == reflect.String {
-		if idField.Type().ConvertibleTo(reflect.TypeOf("")) {
-			converted := reflect.ValueOf(id.Hex()).Convert(idField.Type())
-			idField.Set(converted)
-		}
-	}
+	SetIdFieldValue(idField, id)
 }
 
 func (d *DocumentRepository[Entity]) ToObjectIdWithCheck(id interface{}) (primitive.ObjectID, bool) {
+	return ToObjectIdWithCheck(id)
+}
+func (d *DocumentRepository[Entity]) ToObjectId(id interface{}) primitive.ObjectID {
+	v, _ := d.ToObjectIdWithCheck(id)
+	return v
+}
+
+// GetCollection 返回构造时的默认集合。设置了集合解析器时不会按租户解析，直接使用该集合会绕过租户隔离，
+// 此时应使用 GetCollectionWithContext。
+//
+// Deprecated: 使用 GetCollectionWithContext，按 context 解析实际使用的集合
+func (d *DocumentRepository[Entity]) GetCollection() *mongo.Collection {
+	return d.collection
+}
+
+// GetCollectionName returns the collection name for the given entity.
+// 判断 emptyEntity 是否实现了 mongoxentity.EntityDocument 接口，如果是，则调用其 CollectionName 方法获取集合名称。(同时支持，值和指针两种方式)
+// 如果不是，则使用反射获取结构体名称，并转换为 snake_case 格式。
+func GetCollectionName(emptyEntity any) string {
+	// 优先判断是否实现 mongoxentity.EntityDocument 接口（值类型和指针类型都支持）
+	if v, ok := emptyEntity.(mongoxentity.EntityDocument); ok {
+		return v.CollectionName()
+	}
+
+	entityType := reflect.TypeOf(emptyEntity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem() // 获取指针指向的类型
+	}
+	structName := entityType.Name()
+	return toSnakeCase(structName)
+}
+
+// ToObjectIdWithCheck 将 id 转换为 ObjectID，支持 primitive.ObjectID、BaseObjectId、十六进制字符串及其包装类型
+func ToObjectIdWithCheck(id interface{}) (primitive.ObjectID, bool) {
 	if nil == id {
 		return primitive.NilObjectID, false
 	}
@@ -329,30 +644,37 @@ func (d *DocumentRepository[Entity]) ToObjectIdWithCheck(id interface{}) (primit
 	}
 	return primitive.NilObjectID, false
 }
-func (d *DocumentRepository[Entity]) ToObjectId(id interface{}) primitive.ObjectID {
-	v, _ := d.ToObjectIdWithCheck(id)
-	return v
-}
 
-func (d *DocumentRepository[Entity]) GetCollection() *mongo.Collection {
-	return d.collection
-}
+// GetIdFieldValue 获取实体指针中名为 fieldName 的 ID 字段
+func GetIdFieldValue(doc interface{}, fieldName string) (reflect.Value, bool) {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, false
+	}
+	elem := v.Elem()
+	if elem.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
 
-// GetCollectionName returns the collection name for the given entity.
-// 判断 emptyEntity 是否实现了 mongoxentity.EntityDocument 接口，如果是，则调用其 CollectionName 方法获取集合名称。(同时支持，值和指针两种方式)
-// 如果不是，则使用反射获取结构体名称，并转换为 snake_case 格式。
-func GetCollectionName(emptyEntity any) string {
-	// 优先判断是否实现 mongoxentity.EntityDocument 接口（值类型和指针类型都支持）
-	if v, ok := emptyEntity.(mongoxentity.EntityDocument); ok {
-		return v.CollectionName()
+	// 查找 ID 字段
+	idField := elem.FieldByName(fieldName)
+	//if !idField.IsValid() || idField.Type() != reflect.TypeOf(primitive.ObjectID{}) {
+	if !idField.IsValid() {
+		return reflect.Value{}, false
 	}
+	return idField, true
+}
 
-	entityType := reflect.TypeOf(emptyEntity)
-	if entityType.Kind() == reflect.Ptr {
-		entityType = entityType.Elem() // 获取指针指向的类型
+// SetIdFieldValue 设置 ID 字段，支持 primitive.ObjectID 与字符串类型（如 mongoxentity.StrObjectId）
+func SetIdFieldValue(idField reflect.Value, id primitive.ObjectID) {
+	if idField.Type() == reflect.TypeOf(primitive.ObjectID{}) {
+		idField.Set(reflect.ValueOf(id))
+	} else if idField.Kind() == reflect.String {
+		if idField.Type().ConvertibleTo(reflect.TypeOf("")) {
+			converted := reflect.ValueOf(id.Hex()).Convert(idField.Type())
+			idField.Set(converted)
+		}
 	}
-	structName := entityType.Name()
-	return toSnakeCase(structName)
 }
 
 // toSnakeCase converts a CamelCase string to snake_case.
@@ -0,0 +1,209 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/aomi-go/data/common/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StringMatcher 字符串字段的匹配方式
+type StringMatcher string
+
+const (
+	StringExact    = StringMatcher("exact")
+	StringPrefix   = StringMatcher("prefix")
+	StringContains = StringMatcher("contains")
+)
+
+// NewExampleMatcher 创建默认的示例匹配配置：忽略零值，字符串精确匹配且区分大小写
+func NewExampleMatcher() *ExampleMatcher {
+	return &ExampleMatcher{stringMatcher: StringExact, ignoredPaths: map[string]bool{}}
+}
+
+// ExampleMatcher 按示例查询的匹配配置
+type ExampleMatcher struct {
+	includeZeroValues bool
+	stringMatcher     StringMatcher
+	ignoreCase        bool
+	ignoredPaths      map[string]bool
+}
+
+// WithIncludeZeroValues 零值字段同样作为查询条件，带 omitempty 标签的字段（如 _id）与零值 _id 除外
+func (m *ExampleMatcher) WithIncludeZeroValues() *ExampleMatcher {
+	m.includeZeroValues = true
+	return m
+}
+
+// WithStringMatcher 设置字符串字段的匹配方式
+func (m *ExampleMatcher) WithStringMatcher(stringMatcher StringMatcher) *ExampleMatcher {
+	m.stringMatcher = stringMatcher
+	return m
+}
+
+// WithIgnoreCase 字符串字段忽略大小写
+func (m *ExampleMatcher) WithIgnoreCase() *ExampleMatcher {
+	m.ignoreCase = true
+	return m
+}
+
+// WithIgnorePaths 忽略指定的 bson 路径（例如 "address.city"）
+func (m *ExampleMatcher) WithIgnorePaths(paths ...string) *ExampleMatcher {
+	for _, p := range paths {
+		m.ignoredPaths[p] = true
+	}
+	return m
+}
+
+// ExampleFilter 根据实体的 bson 标签从示例中生成过滤条件。
+// 嵌套结构体展开为点号路径，inline 结构体平铺，nil 指针不参与匹配；
+// 切片、数组与 map 字段（primitive.ObjectID 除外）无论是否为空都不参与匹配，需要时请在返回的过滤条件上自行添加。
+func ExampleFilter(example interface{}, matcher *ExampleMatcher) (bson.M, error) {
+	if nil == matcher {
+		matcher = NewExampleMatcher()
+	}
+	filter := bson.M{}
+	v := reflect.ValueOf(example)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return filter, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mongo: example must be a struct, got %s", v.Kind())
+	}
+	if err := matcher.collect(filter, "", v); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (m *ExampleMatcher) collect(filter bson.M, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := bsoncodec.DefaultStructTagParser.ParseStructTags(sf)
+		if err != nil {
+			return err
+		}
+		if tags.Skip {
+			continue
+		}
+		field := v.Field(i)
+		if tags.Inline {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					continue
+				}
+				field = field.Elem()
+			}
+			if field.Kind() == reflect.Struct {
+				if err := m.collect(filter, prefix, field); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path := tags.Name
+		if "" != prefix {
+			path = prefix + "." + tags.Name
+		}
+		if m.ignoredPaths[path] {
+			continue
+		}
+
+		if field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+			// 非 nil 指针即使指向零值也作为条件，便于显式匹配 false、0
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		} else if field.IsZero() && (!m.includeZeroValues || tags.OmitEmpty || "_id" == path) {
+			// omitempty 字段的零值在保存时不会写入，不能作为相等条件
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Array:
+			// 集合字段的相等条件要求元素与顺序完全一致，不适合按示例匹配，统一忽略
+			if field.Type() == reflect.TypeOf(primitive.ObjectID{}) {
+				filter[path] = field.Interface()
+			}
+			continue
+		case reflect.Struct:
+			if hasExportedFields(field.Type()) {
+				if err := m.collect(filter, path, field); err != nil {
+					return err
+				}
+				continue
+			}
+		case reflect.String:
+			filter[path] = m.stringCondition(field.Interface(), field.String())
+			continue
+		}
+		filter[path] = field.Interface()
+	}
+	return nil
+}
+
+// stringCondition 生成字符串字段条件；自定义字符串类型（如 mongoxentity.StrObjectId）保持精确匹配，交给编解码器处理
+func (m *ExampleMatcher) stringCondition(raw interface{}, s string) interface{} {
+	if _, ok := raw.(string); !ok {
+		return raw
+	}
+	if m.stringMatcher == StringExact && !m.ignoreCase {
+		return s
+	}
+	pattern := regexp.QuoteMeta(s)
+	switch m.stringMatcher {
+	case StringPrefix:
+		pattern = "^" + pattern
+	case StringContains:
+	default:
+		pattern = "^" + pattern + "$"
+	}
+	opts := ""
+	if m.ignoreCase {
+		opts = "i"
+	}
+	return bson.M{"$regex": primitive.Regex{Pattern: pattern, Options: opts}}
+}
+
+// hasExportedFields time.Time、decimal.Decimal 等没有导出字段的结构体作为整体值匹配
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByExample 按示例查询
+func (d *DocumentRepository[Entity]) FindByExample(ctx context.Context, example *Entity, matcher *ExampleMatcher, opts ...*options.FindOptions) ([]*Entity, error) {
+	filter, err := ExampleFilter(example, matcher)
+	if err != nil {
+		return nil, err
+	}
+	return d.Find(ctx, filter, opts...)
+}
+
+// QueryWithPageByExample 按示例分页查询
+func (d *DocumentRepository[Entity]) QueryWithPageByExample(ctx context.Context, example *Entity, matcher *ExampleMatcher, pageable *page.Pageable) (*page.Page[Entity], error) {
+	filter, err := ExampleFilter(example, matcher)
+	if err != nil {
+		return nil, err
+	}
+	return d.QueryWithPage(ctx, filter, pageable)
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exampleAddress struct {
+	City string `bson:"city"`
+	Zip  string `bson:"zip"`
+}
+
+type ExampleBase struct {
+	Tenant string `bson:"tenant"`
+}
+
+type exampleUser struct {
+	ExampleBase `bson:",inline"`
+	ID          mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Name        string                   `bson:"name"`
+	Age         int                      `bson:"age"`
+	Enabled     *bool                    `bson:"enabled"`
+	Address     exampleAddress           `bson:"address"`
+	Tags        []string                 `bson:"tags"`
+	CreatedAt   time.Time                `bson:"created_at"`
+	Secret      string                   `bson:"-"`
+}
+
+func TestExampleFilter(t *testing.T) {
+	enabled := false
+	example := &exampleUser{
+		ExampleBase: ExampleBase{Tenant: "t1"},
+		Name:        "a.b",
+		Enabled:     &enabled,
+		Address:     exampleAddress{City: "Shanghai"},
+		Tags:        []string{"x"},
+		Secret:      "s",
+	}
+
+	filter, err := ExampleFilter(example, NewExampleMatcher())
+	if err != nil {
+		t.Fatalf("ExampleFilter() error = %v", err)
+	}
+	want := bson.M{"tenant": "t1", "name": "a.b", "enabled": false, "address.city": "Shanghai"}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("ExampleFilter() = %v, want %v", filter, want)
+	}
+
+	filter, _ = ExampleFilter(example, NewExampleMatcher().
+		WithStringMatcher(StringPrefix).
+		WithIgnoreCase().
+		WithIgnorePaths("address.city", "tenant"))
+	want = bson.M{"name": bson.M{"$regex": primitive.Regex{Pattern: `^a\.b`, Options: "i"}}, "enabled": false}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("ExampleFilter() = %v, want %v", filter, want)
+	}
+
+	filter, _ = ExampleFilter(&exampleUser{ID: "68773f19dcfdef2276d06ad6"}, NewExampleMatcher().WithStringMatcher(StringContains))
+	if filter["_id"] != mongoxentity.StrObjectId("68773f19dcfdef2276d06ad6") {
+		t.Errorf("ExampleFilter() _id = %v", filter["_id"])
+	}
+
+	filter, _ = ExampleFilter(&exampleUser{Name: "a"}, NewExampleMatcher().WithIncludeZeroValues().WithIgnorePaths("created_at"))
+	want = bson.M{"tenant": "", "name": "a", "age": 0, "address.city": "", "address.zip": ""}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("ExampleFilter() with zero values = %v, want %v", filter, want)
+	}
+}
@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scope 为过滤条件追加仓储级别的限定条件（租户、排除已软删除的数据）
+func (d *DocumentRepository[Entity]) scope(ctx context.Context, filter interface{}) (interface{}, error) {
+	filter, err := d.tenantScope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if d.softDelete && !IsIncludeDeleted(ctx) {
+		filter = andFilter(filter, notDeletedFilter())
+	}
+	return filter, nil
+}
+
+// andFilter 以 $and 合并过滤条件，filter 为空时直接返回 extra
+func andFilter(filter interface{}, extra bson.M) interface{} {
+	if isEmptyFilter(filter) {
+		return extra
+	}
+	return bson.M{"$and": bson.A{filter, extra}}
+}
+
+func isEmptyFilter(filter interface{}) bool {
+	if nil == filter {
+		return true
+	}
+	v := reflect.ValueOf(filter)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+)
+
+func beforeSave(ctx context.Context, entity interface{}) error {
+	if h, ok := entity.(mongoxentity.BeforeSaveHook); ok {
+		return h.BeforeSave(ctx)
+	}
+	return nil
+}
+
+func afterSave(ctx context.Context, entity interface{}) error {
+	if h, ok := entity.(mongoxentity.AfterSaveHook); ok {
+		return h.AfterSave(ctx)
+	}
+	return nil
+}
+
+func afterLoad(ctx context.Context, entity interface{}) error {
+	if h, ok := entity.(mongoxentity.AfterLoadHook); ok {
+		return h.AfterLoad(ctx)
+	}
+	return nil
+}
+
+// hasBeforeDelete 实体类型是否实现了 mongoxentity.BeforeDeleteHook，未实现时删除无需先加载数据
+func (d *DocumentRepository[Entity]) hasBeforeDelete() bool {
+	var e interface{} = new(Entity)
+	_, ok := e.(mongoxentity.BeforeDeleteHook)
+	return ok
+}
+
+// beforeDelete 加载满足条件的实体并依次调用 BeforeDelete
+func (d *DocumentRepository[Entity]) beforeDelete(ctx context.Context, filter interface{}) error {
+	if !d.hasBeforeDelete() {
+		return nil
+	}
+	entities, err := d.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, entity := range entities {
+		var e interface{} = entity
+		if err := e.(mongoxentity.BeforeDeleteHook).BeforeDelete(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type hookedUser struct {
+	Name   string `bson:"name"`
+	Loaded bool   `bson:"-"`
+}
+
+func (u *hookedUser) BeforeSave(ctx context.Context) error {
+	if "" == u.Name {
+		return errors.New("name is required")
+	}
+	u.Name = strings.TrimSpace(u.Name)
+	return nil
+}
+
+func (u *hookedUser) AfterLoad(ctx context.Context) error {
+	u.Loaded = true
+	return nil
+}
+
+func (u *hookedUser) BeforeDelete(ctx context.Context) error {
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.TODO()
+	repo := &DocumentRepository[hookedUser]{}
+
+	if _, err := repo.Save(ctx, &hookedUser{}); err == nil || err.Error() != "name is required" {
+		t.Errorf("Save() error = %v, want BeforeSave error", err)
+	}
+
+	u := &hookedUser{Name: " a "}
+	if err := beforeSave(ctx, u); err != nil || u.Name != "a" {
+		t.Errorf("beforeSave() = %v, name %q", err, u.Name)
+	}
+	if err := afterLoad(ctx, u); err != nil || !u.Loaded {
+		t.Errorf("afterLoad() = %v, loaded %v", err, u.Loaded)
+	}
+	if !repo.hasBeforeDelete() {
+		t.Errorf("hasBeforeDelete() = false")
+	}
+	if (&DocumentRepository[User]{}).hasBeforeDelete() {
+		t.Errorf("hasBeforeDelete() for User = true")
+	}
+}
@@ -0,0 +1,502 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aomi-go/data/mongo/mongoxcodec"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexesOf 收集实体声明的索引：mongox 标签与 mongoxentity.Indexed 接口。
+//
+// mongox 标签的选项以逗号分隔：
+//
+//	index        单字段升序索引
+//	index=name   加入名为 name 的复合索引，字段顺序即结构体中的顺序
+//	desc         降序
+//	unique       唯一索引，单独使用时为单字段唯一索引
+//	sparse       稀疏索引
+//	ttl=720h     TTL 索引，值为 time.ParseDuration 格式
+//	text         加入集合的全文索引
+//
+// 部分索引只能通过 mongoxentity.Indexed 声明
+func IndexesOf[Entity interface{}]() ([]mongoxentity.Index, error) {
+	var entity Entity
+	c := &indexCollector{named: map[string]*mongoxentity.Index{}}
+	if t := reflect.TypeOf(entity); nil != t && t.Kind() == reflect.Struct {
+		if err := c.collect(t, "", map[reflect.Type]bool{t: true}); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.text) > 0 {
+		c.indexes = append(c.indexes, &mongoxentity.Index{Keys: c.text})
+	}
+
+	indexes := make([]mongoxentity.Index, 0, len(c.indexes))
+	for _, index := range c.indexes {
+		indexes = append(indexes, *index)
+	}
+	if v, ok := interface{}(&entity).(mongoxentity.Indexed); ok {
+		indexes = append(indexes, v.Indexes()...)
+	}
+
+	names := map[string]bool{}
+	for i := range indexes {
+		if "" == indexes[i].Name {
+			indexes[i].Name = IndexName(indexes[i].Keys)
+		}
+		if names[indexes[i].Name] {
+			return nil, fmt.Errorf("mongo: duplicate index %s", indexes[i].Name)
+		}
+		names[indexes[i].Name] = true
+	}
+	return indexes, nil
+}
+
+// IndexName MongoDB 默认的索引名称，如 user_id_1_created_at_-1
+func IndexName(keys bson.D) string {
+	parts := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		parts = append(parts, k.Key, fmt.Sprint(k.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+type indexCollector struct {
+	indexes []*mongoxentity.Index
+	// named 通过 index=name 声明的复合索引
+	named map[string]*mongoxentity.Index
+	text  bson.D
+}
+
+func (c *indexCollector) collect(t reflect.Type, prefix string, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := bsoncodec.DefaultStructTagParser.ParseStructTags(sf)
+		if err != nil {
+			return err
+		}
+		if tags.Skip {
+			continue
+		}
+		key := tags.Name
+		if "" != prefix {
+			key = prefix + "." + key
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != timeType && !visiting[ft] {
+			nested := key
+			if tags.Inline {
+				nested = prefix
+			}
+			visiting[ft] = true
+			err := c.collect(ft, nested, visiting)
+			delete(visiting, ft)
+			if err != nil {
+				return err
+			}
+		}
+
+		if tag, ok := sf.Tag.Lookup("mongox"); ok {
+			if err := c.add(key, tag); err != nil {
+				return fmt.Errorf("mongo: field %s: %w", sf.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// add 按 mongox 标签为字段 key 添加索引
+func (c *indexCollector) add(key string, tag string) error {
+	var (
+		name    string
+		indexed bool
+		index   mongoxentity.Index
+	)
+	direction := 1
+	for _, part := range strings.Split(tag, ",") {
+		option, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch option {
+		case "index":
+			indexed, name = true, value
+		case "desc":
+			direction = -1
+		case "unique":
+			indexed, index.Unique = true, true
+		case "sparse":
+			index.Sparse = true
+		case "ttl":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid ttl %q: %w", value, err)
+			}
+			if d < 0 || d/time.Second > math.MaxInt32 {
+				return fmt.Errorf("ttl %q out of range [0s, %ds]", value, math.MaxInt32)
+			}
+			seconds := int32(d / time.Second)
+			indexed, index.ExpireAfterSeconds = true, &seconds
+		case "text":
+			c.text = append(c.text, bson.E{Key: key, Value: "text"})
+		}
+	}
+	if !indexed {
+		return nil
+	}
+
+	keys := bson.E{Key: key, Value: direction}
+	if "" == name {
+		index.Keys = bson.D{keys}
+		c.indexes = append(c.indexes, &index)
+		return nil
+	}
+	if nil != index.ExpireAfterSeconds {
+		return fmt.Errorf("ttl index %s must be single field", name)
+	}
+	compound, ok := c.named[name]
+	if !ok {
+		compound = &mongoxentity.Index{Name: name}
+		c.named[name] = compound
+		c.indexes = append(c.indexes, compound)
+	}
+	compound.Keys = append(compound.Keys, keys)
+	compound.Unique = compound.Unique || index.Unique
+	compound.Sparse = compound.Sparse || index.Sparse
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// IndexDrift 集合中已存在但与声明不一致的索引
+type IndexDrift struct {
+	Expected mongoxentity.Index
+	Actual   mongoxentity.Index
+	// Reason 不一致的项，如 keys、unique、name
+	Reason string
+}
+
+// IndexReport EnsureIndexes 的结果
+type IndexReport struct {
+	DryRun bool
+	// Created 创建的索引，试运行时为将要创建的索引
+	Created []mongoxentity.Index
+	// Drifted 与声明不一致的索引，不会自动删除或重建，需要人工处理
+	Drifted []IndexDrift
+	// Unmanaged 集合中存在但未声明的索引，不含 _id_
+	Unmanaged []string
+}
+
+// HasDrift 是否存在与声明不一致的索引
+func (r *IndexReport) HasDrift() bool {
+	return len(r.Drifted) > 0
+}
+
+// EnsureIndexes 创建实体声明但集合中缺失的索引，并报告与已有索引的差异（见 IndexesOf）。
+// dryRun 为 true 时只返回报告，不创建索引。按租户路由集合时只处理 ctx 对应的集合
+func (d *DocumentRepository[Entity]) EnsureIndexes(ctx context.Context, dryRun bool) (*IndexReport, error) {
+	expected, err := IndexesOf[Entity]()
+	if err != nil {
+		return nil, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := listIndexes(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := planIndexes(expected, existing)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+	if dryRun || len(report.Created) == 0 {
+		return report, nil
+	}
+
+	models := make([]mongo.IndexModel, 0, len(report.Created))
+	for _, index := range report.Created {
+		models = append(models, indexModel(index))
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, models); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func indexModel(index mongoxentity.Index) mongo.IndexModel {
+	opts := options.Index().SetName(index.Name)
+	if index.Unique {
+		opts.SetUnique(true)
+	}
+	if index.Sparse {
+		opts.SetSparse(true)
+	}
+	if nil != index.ExpireAfterSeconds {
+		opts.SetExpireAfterSeconds(*index.ExpireAfterSeconds)
+	}
+	if nil != index.PartialFilter {
+		opts.SetPartialFilterExpression(index.PartialFilter)
+	}
+	return mongo.IndexModel{Keys: index.Keys, Options: opts}
+}
+
+// indexSpec listIndexes 返回的索引描述
+type indexSpec struct {
+	Name                    string   `bson:"name"`
+	Key                     bson.D   `bson:"key"`
+	Unique                  bool     `bson:"unique"`
+	Sparse                  bool     `bson:"sparse"`
+	ExpireAfterSeconds      *int32   `bson:"expireAfterSeconds"`
+	PartialFilterExpression bson.Raw `bson:"partialFilterExpression"`
+	Weights                 bson.D   `bson:"weights"`
+}
+
+func listIndexes(ctx context.Context, collection *mongo.Collection) ([]mongoxentity.Index, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []indexSpec
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+	indexes := make([]mongoxentity.Index, 0, len(specs))
+	for _, spec := range specs {
+		indexes = append(indexes, spec.index())
+	}
+	return indexes, nil
+}
+
+// index 转换为声明的形式，全文索引的 _fts、_ftsx 还原为 weights 中的字段
+func (s indexSpec) index() mongoxentity.Index {
+	index := mongoxentity.Index{
+		Name:               s.Name,
+		Keys:               s.Key,
+		Unique:             s.Unique,
+		Sparse:             s.Sparse,
+		ExpireAfterSeconds: s.ExpireAfterSeconds,
+	}
+	if len(s.PartialFilterExpression) > 0 {
+		index.PartialFilter = s.PartialFilterExpression
+	}
+	if len(s.Weights) > 0 {
+		keys := bson.D{}
+		for _, k := range s.Key {
+			if "_fts" != k.Key && "_ftsx" != k.Key {
+				keys = append(keys, k)
+			}
+		}
+		for _, w := range s.Weights {
+			keys = append(keys, bson.E{Key: w.Key, Value: "text"})
+		}
+		index.Keys = keys
+	}
+	return index
+}
+
+// planIndexes 对比声明与已有的索引：按名称匹配，名称不同但字段相同的视为改名
+func planIndexes(expected []mongoxentity.Index, existing []mongoxentity.Index) (*IndexReport, error) {
+	report := &IndexReport{}
+	matched := map[string]bool{}
+	for _, want := range expected {
+		var actual *mongoxentity.Index
+		for i := range existing {
+			if existing[i].Name == want.Name {
+				actual = &existing[i]
+				break
+			}
+		}
+		if nil == actual {
+			for i := range existing {
+				if !matched[existing[i].Name] && sameKeys(want.Keys, existing[i].Keys) {
+					actual = &existing[i]
+					break
+				}
+			}
+		}
+		if nil == actual {
+			report.Created = append(report.Created, want)
+			continue
+		}
+		matched[actual.Name] = true
+		reason, err := indexDiff(want, *actual)
+		if err != nil {
+			return nil, err
+		}
+		if "" != reason {
+			report.Drifted = append(report.Drifted, IndexDrift{Expected: want, Actual: *actual, Reason: reason})
+		}
+	}
+	for _, index := range existing {
+		if !matched[index.Name] && "_id_" != index.Name {
+			report.Unmanaged = append(report.Unmanaged, index.Name)
+		}
+	}
+	return report, nil
+}
+
+// indexDiff 返回不一致的项，逗号分隔，一致时为空
+func indexDiff(expected mongoxentity.Index, actual mongoxentity.Index) (string, error) {
+	var reasons []string
+	if expected.Name != actual.Name {
+		reasons = append(reasons, "name")
+	}
+	if !sameKeys(expected.Keys, actual.Keys) {
+		reasons = append(reasons, "keys")
+	}
+	if expected.Unique != actual.Unique {
+		reasons = append(reasons, "unique")
+	}
+	if expected.Sparse != actual.Sparse {
+		reasons = append(reasons, "sparse")
+	}
+	if (nil == expected.ExpireAfterSeconds) != (nil == actual.ExpireAfterSeconds) ||
+		(nil != expected.ExpireAfterSeconds && *expected.ExpireAfterSeconds != *actual.ExpireAfterSeconds) {
+		reasons = append(reasons, "ttl")
+	}
+	same, err := samePartialFilter(expected.PartialFilter, actual.PartialFilter)
+	if err != nil {
+		return "", err
+	}
+	if !same {
+		reasons = append(reasons, "partial filter")
+	}
+	return strings.Join(reasons, ","), nil
+}
+
+// sameKeys 比较索引字段与方向，数值统一比较，全文索引的字段不区分顺序
+func sameKeys(a bson.D, b bson.D) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	text := map[string]int{}
+	for i := range a {
+		if "text" == a[i].Value {
+			text[a[i].Key]++
+		}
+		if "text" == b[i].Value {
+			text[b[i].Key]--
+		}
+		if "text" == a[i].Value && "text" == b[i].Value {
+			continue
+		}
+		if a[i].Key != b[i].Key || !sameKeyValue(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	for _, n := range text {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sameKeyValue(a interface{}, b interface{}) bool {
+	x, xok := toFloat(a)
+	y, yok := toFloat(b)
+	if xok && yok {
+		return x == y
+	}
+	return a == b
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// samePartialFilter 比较部分索引的过滤条件，文档字段不区分顺序（bson.M 编码后的顺序不固定）
+func samePartialFilter(a interface{}, b interface{}) (bool, error) {
+	if nil == a || nil == b {
+		return nil == a && nil == b, nil
+	}
+	x, err := marshalFilter(a)
+	if err != nil {
+		return false, err
+	}
+	y, err := marshalFilter(b)
+	if err != nil {
+		return false, err
+	}
+	return sameDocument(x, y), nil
+}
+
+func sameDocument(a bson.Raw, b bson.Raw) bool {
+	x, err := a.Elements()
+	if err != nil {
+		return false
+	}
+	y, err := b.Elements()
+	if err != nil || len(x) != len(y) {
+		return false
+	}
+	for _, e := range x {
+		other, err := b.LookupErr(e.Key())
+		if err != nil || !sameValue(e.Value(), other) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a bson.RawValue, b bson.RawValue) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case bson.TypeEmbeddedDocument:
+		return sameDocument(a.Document(), b.Document())
+	case bson.TypeArray:
+		x, err := a.Array().Values()
+		if err != nil {
+			return false
+		}
+		y, err := b.Array().Values()
+		if err != nil || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !sameValue(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return bytes.Equal(a.Value, b.Value)
+}
+
+func marshalFilter(filter interface{}) ([]byte, error) {
+	if raw, ok := filter.(bson.Raw); ok {
+		return raw, nil
+	}
+	return bson.MarshalWithRegistry(mongoxcodec.NewRegistry(), filter)
+}
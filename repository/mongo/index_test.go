@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type indexedAddress struct {
+	City string `bson:"city" mongox:"index"`
+}
+
+type indexedOrder struct {
+	Id                       mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	mongoxentity.AuditFields `bson:",inline"`
+	UserId                   string         `bson:"user_id" mongox:"index=user_created"`
+	CreatedOn                time.Time      `bson:"created_on" mongox:"index=user_created,desc"`
+	No                       string         `bson:"no" mongox:"unique"`
+	Coupon                   string         `bson:"coupon" mongox:"index,sparse"`
+	ExpireAt                 time.Time      `bson:"expire_at" mongox:"ttl=24h"`
+	Title                    string         `bson:"title" mongox:"text"`
+	Remark                   string         `bson:"remark" mongox:"text"`
+	Address                  indexedAddress `bson:"address"`
+	Version                  int64          `bson:"version" mongox:"version"`
+}
+
+func (o *indexedOrder) Indexes() []mongoxentity.Index {
+	return []mongoxentity.Index{{
+		Keys:          bson.D{{Key: "status", Value: 1}},
+		PartialFilter: bson.D{{Key: "status", Value: bson.D{{Key: "$exists", Value: true}}}},
+	}}
+}
+
+func TestIndexesOf(t *testing.T) {
+	indexes, err := IndexesOf[indexedOrder]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]mongoxentity.Index{}
+	for _, index := range indexes {
+		byName[index.Name] = index
+	}
+	if len(byName) != 7 {
+		t.Fatalf("IndexesOf() = %v", indexes)
+	}
+
+	compound := byName["user_created"]
+	if !sameKeys(compound.Keys, bson.D{{Key: "user_id", Value: 1}, {Key: "created_on", Value: -1}}) {
+		t.Errorf("compound index keys = %v", compound.Keys)
+	}
+	if !byName["no_1"].Unique || !byName["coupon_1"].Sparse || byName["coupon_1"].Unique {
+		t.Errorf("unique/sparse indexes = %v, %v", byName["no_1"], byName["coupon_1"])
+	}
+	if ttl := byName["expire_at_1"].ExpireAfterSeconds; nil == ttl || *ttl != 86400 {
+		t.Errorf("ttl index = %v", byName["expire_at_1"])
+	}
+	if _, ok := byName["title_text_remark_text"]; !ok {
+		t.Errorf("text index missing: %v", indexes)
+	}
+	if _, ok := byName["address.city_1"]; !ok {
+		t.Errorf("nested index missing: %v", indexes)
+	}
+	if nil == byName["status_1"].PartialFilter {
+		t.Errorf("interface index missing: %v", indexes)
+	}
+}
+
+type invalidTTL struct {
+	ExpireAt time.Time `bson:"expire_at" mongox:"ttl=soon"`
+}
+
+type overflowTTL struct {
+	ExpireAt time.Time `bson:"expire_at" mongox:"ttl=600000h"`
+}
+
+func TestIndexesOfInvalid(t *testing.T) {
+	if _, err := IndexesOf[invalidTTL](); err == nil {
+		t.Error("IndexesOf() with invalid ttl, want error")
+	}
+	if _, err := IndexesOf[overflowTTL](); err == nil {
+		t.Error("IndexesOf() with ttl overflowing int32, want error")
+	}
+}
+
+func TestSamePartialFilter(t *testing.T) {
+	expected := bson.M{"status": "active", "age": bson.M{"$gt": 18, "$lt": 60}, "tags": bson.A{"a", "b"}}
+	stored, _ := bson.Marshal(bson.D{
+		{Key: "tags", Value: bson.A{"a", "b"}},
+		{Key: "age", Value: bson.D{{Key: "$lt", Value: 60}, {Key: "$gt", Value: 18}}},
+		{Key: "status", Value: "active"},
+	})
+	for i := 0; i < 10; i++ {
+		if same, err := samePartialFilter(expected, bson.Raw(stored)); err != nil || !same {
+			t.Fatalf("samePartialFilter() = %v, %v", same, err)
+		}
+	}
+	reordered, _ := bson.Marshal(bson.D{{Key: "tags", Value: bson.A{"b", "a"}}, {Key: "status", Value: "active"}, {Key: "age", Value: bson.M{"$gt": 18, "$lt": 60}}})
+	if same, _ := samePartialFilter(expected, bson.Raw(reordered)); same {
+		t.Error("samePartialFilter() ignored array order")
+	}
+}
+
+func TestPlanIndexes(t *testing.T) {
+	ttl := int32(3600)
+	expected := []mongoxentity.Index{
+		{Name: "no_1", Keys: bson.D{{Key: "no", Value: 1}}, Unique: true},
+		{Name: "user_created", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_on", Value: -1}}},
+		{Name: "expire_at_1", Keys: bson.D{{Key: "expire_at", Value: 1}}, ExpireAfterSeconds: &ttl},
+		{Name: "title_text_remark_text", Keys: bson.D{{Key: "title", Value: "text"}, {Key: "remark", Value: "text"}}},
+		{Name: "status_1", Keys: bson.D{{Key: "status", Value: 1}}, PartialFilter: bson.D{{Key: "status", Value: bson.D{{Key: "$exists", Value: true}}}}},
+	}
+	partial, _ := bson.Marshal(bson.D{{Key: "status", Value: bson.D{{Key: "$exists", Value: true}}}})
+	existing := []mongoxentity.Index{
+		{Name: "_id_", Keys: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "no_1", Keys: bson.D{{Key: "no", Value: int32(1)}}},
+		{Name: "idx_user", Keys: bson.D{{Key: "user_id", Value: int32(1)}, {Key: "created_on", Value: float64(-1)}}},
+		(indexSpec{
+			Name:    "title_text_remark_text",
+			Key:     bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+			Weights: bson.D{{Key: "remark", Value: int32(1)}, {Key: "title", Value: int32(1)}},
+		}).index(),
+		{Name: "status_1", Keys: bson.D{{Key: "status", Value: int32(1)}}, PartialFilter: bson.Raw(partial)},
+		{Name: "legacy_1", Keys: bson.D{{Key: "legacy", Value: int32(1)}}},
+	}
+
+	report, err := planIndexes(expected, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Created) != 1 || report.Created[0].Name != "expire_at_1" {
+		t.Errorf("Created = %v", report.Created)
+	}
+	if len(report.Drifted) != 2 || report.Drifted[0].Reason != "unique" || report.Drifted[1].Reason != "name" {
+		t.Errorf("Drifted = %+v", report.Drifted)
+	}
+	if len(report.Unmanaged) != 1 || report.Unmanaged[0] != "legacy_1" {
+		t.Errorf("Unmanaged = %v", report.Unmanaged)
+	}
+	if !report.HasDrift() {
+		t.Error("HasDrift() = false")
+	}
+}
@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/repository/instrument"
+)
+
+// SetInstrumentation 开启埋点，记录直接访问数据库的操作（Save、Find、Count、Delete 等）的耗时、错误数、文档数与慢操作，
+// 并为每次操作创建追踪 span；FindAll、QueryWithPage 等组合操作通过其内部的操作记录
+func (d *DocumentRepository[Entity]) SetInstrumentation(instrumentation *instrument.Instrumentation) *DocumentRepository[Entity] {
+	d.instrumentation = instrumentation
+	return d
+}
+
+// observe 开始记录操作，返回的函数在操作结束时传入文档数与错误
+func (d *DocumentRepository[Entity]) observe(ctx context.Context, operation string) (context.Context, func(documents int64, err error)) {
+	if nil == d.instrumentation {
+		return ctx, func(int64, error) {}
+	}
+	ctx, op := d.instrumentation.Start(ctx, d.collectionName, operation)
+	return ctx, func(documents int64, err error) {
+		// 未找到数据不作为错误统计
+		if errors.Is(err, common.ErrNoResult) {
+			err = nil
+		}
+		op.End(documents, err)
+	}
+}
+
+// countOf 单个实体的文档数
+func countOf[T interface{}](entity *T) int64 {
+	if nil == entity {
+		return 0
+	}
+	return 1
+}
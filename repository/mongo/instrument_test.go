@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/repository/instrument"
+)
+
+func TestObserve(t *testing.T) {
+	exporter := instrument.NewMemoryExporter()
+	tracer := instrument.NewMemoryTracer()
+	repo := (&DocumentRepository[auditedUser]{collectionName: "users"}).
+		SetInstrumentation(instrument.NewInstrumentation(exporter).SetTracer(tracer))
+
+	ctx, done := repo.observe(context.TODO(), OpFindById)
+	if _, ok := instrument.SpanFromContext(ctx); !ok {
+		t.Fatalf("observe() did not start a span")
+	}
+	done(0, common.ErrNoResult)
+
+	s, ok := exporter.Stats("users", OpFindById)
+	if !ok || s.Count != 1 || s.Errors != 0 {
+		t.Fatalf("Stats() = %+v, %v", s, ok)
+	}
+	if spans := tracer.Spans(); len(spans) != 1 || spans[0].Name != "users.FindById" {
+		t.Fatalf("Spans() = %+v", spans)
+	}
+
+	// 未开启埋点时不做任何记录
+	_, done = (&DocumentRepository[auditedUser]{}).observe(context.TODO(), OpFind)
+	done(1, nil)
+}
@@ -0,0 +1,285 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/aomi-go/data/common/page"
+	"github.com/aomi-go/data/common/sort"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 仓储操作名称
+const (
+	OpSave             = "Save"
+	OpSaveMany         = "SaveMany"
+	OpFindAll          = "FindAll"
+	OpFindAllById      = "FindAllById"
+	OpFindById         = "FindById"
+	OpExistsById       = "ExistsById"
+	OpDeleteById       = "DeleteById"
+	OpFind             = "Find"
+	OpFindOne          = "FindOne"
+	OpFindOneAndModify = "FindOneAndModify"
+	OpCount            = "Count"
+	OpExist            = "Exist"
+	OpDelete           = "Delete"
+	OpQueryWithPage    = "QueryWithPage"
+	OpQueryWithSort    = "QueryWithSort"
+
+	// 以下操作不在 Repository 接口中，仅用于 DocumentRepository 的埋点
+	OpFindWithCursor = "FindWithCursor"
+	OpUpdateOne      = "UpdateOne"
+	OpUpdateMany     = "UpdateMany"
+	OpRestore        = "Restore"
+	OpPurge          = "Purge"
+	OpAggregate      = "Aggregate"
+)
+
+// Invocation 一次仓储操作的调用信息，拦截器可以在调用 next 之前修改参数，或在之后修改结果
+type Invocation struct {
+	// Operation 操作名称，见 OpSave 等常量
+	Operation string
+	// EntityType 实体类型
+	EntityType reflect.Type
+
+	// Entity Save 时为 *Entity，SaveMany 时为 []*Entity
+	Entity interface{}
+	// IDs FindById、ExistsById、DeleteById、FindAllById 的 ID 参数
+	IDs      []interface{}
+	Filter   interface{}
+	Update   interface{}
+	Pageable *page.Pageable
+	Sort     *sort.Sort
+	// Options 对应操作的选项切片，例如 Find 为 []*options.FindOptions
+	Options interface{}
+
+	// Result 操作结果，类型与 Repository 对应方法的返回值一致
+	Result interface{}
+}
+
+// Handler 执行调用
+type Handler func(ctx context.Context, inv *Invocation) error
+
+// Interceptor 拦截仓储操作，不调用 next 即可短路并通过 inv.Result 返回结果
+type Interceptor func(ctx context.Context, inv *Invocation, next Handler) error
+
+// Intercept 使用有序的拦截器包装仓储，第一个拦截器位于最外层
+func Intercept[Entity interface{}](repo Repository[Entity], interceptors ...Interceptor) Repository[Entity] {
+	return &interceptedRepository[Entity]{
+		target:       repo,
+		interceptors: interceptors,
+		entityType:   reflect.TypeOf((*Entity)(nil)).Elem(),
+	}
+}
+
+type interceptedRepository[Entity interface{}] struct {
+	target       Repository[Entity]
+	interceptors []Interceptor
+	entityType   reflect.Type
+}
+
+// invoke 依次执行拦截器，最终由 call 调用被包装的仓储并写入 inv.Result
+func (r *interceptedRepository[Entity]) invoke(ctx context.Context, inv *Invocation, call func(ctx context.Context, inv *Invocation) (interface{}, error)) error {
+	inv.EntityType = r.entityType
+	handler := func(ctx context.Context, inv *Invocation) error {
+		result, err := call(ctx, inv)
+		inv.Result = result
+		return err
+	}
+	for i := len(r.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := r.interceptors[i], handler
+		handler = func(ctx context.Context, inv *Invocation) error {
+			return interceptor(ctx, inv, next)
+		}
+	}
+	return handler(ctx, inv)
+}
+
+// resultOf 将 inv.Result 转换为方法的返回类型
+func resultOf[T interface{}](inv *Invocation, err error) (T, error) {
+	var zero T
+	if nil != err {
+		return zero, err
+	}
+	if nil == inv.Result {
+		return zero, nil
+	}
+	v, ok := inv.Result.(T)
+	if !ok {
+		return zero, fmt.Errorf("mongo: interceptor returned %T for %s, want %T", inv.Result, inv.Operation, zero)
+	}
+	return v, nil
+}
+
+// optionsOf 读取 inv.Options，拦截器替换为错误的类型时返回错误
+func optionsOf[T interface{}](inv *Invocation) ([]T, error) {
+	if nil == inv.Options {
+		return nil, nil
+	}
+	opts, ok := inv.Options.([]T)
+	if !ok {
+		return nil, fmt.Errorf("mongo: invalid options %T for %s", inv.Options, inv.Operation)
+	}
+	return opts, nil
+}
+
+func (r *interceptedRepository[Entity]) Save(ctx context.Context, entity *Entity) (*Entity, error) {
+	inv := &Invocation{Operation: OpSave, Entity: entity}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		e, ok := inv.Entity.(*Entity)
+		if !ok {
+			return nil, fmt.Errorf("mongo: invalid entity %T for %s", inv.Entity, inv.Operation)
+		}
+		return r.target.Save(ctx, e)
+	})
+	return resultOf[*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) SaveMany(ctx context.Context, entities []*Entity) ([]*Entity, error) {
+	inv := &Invocation{Operation: OpSaveMany, Entity: entities}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		es, ok := inv.Entity.([]*Entity)
+		if !ok {
+			return nil, fmt.Errorf("mongo: invalid entities %T for %s", inv.Entity, inv.Operation)
+		}
+		return r.target.SaveMany(ctx, es)
+	})
+	return resultOf[[]*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) FindAll(ctx context.Context) ([]*Entity, error) {
+	inv := &Invocation{Operation: OpFindAll}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.FindAll(ctx)
+	})
+	return resultOf[[]*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) FindAllById(ctx context.Context, ids ...interface{}) ([]*Entity, error) {
+	inv := &Invocation{Operation: OpFindAllById, IDs: ids}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.FindAllById(ctx, inv.IDs...)
+	})
+	return resultOf[[]*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) FindById(ctx context.Context, id interface{}) (*Entity, error) {
+	inv := &Invocation{Operation: OpFindById, IDs: []interface{}{id}}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.FindById(ctx, firstId(inv))
+	})
+	return resultOf[*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) ExistsById(ctx context.Context, id interface{}) (bool, error) {
+	inv := &Invocation{Operation: OpExistsById, IDs: []interface{}{id}}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.ExistsById(ctx, firstId(inv))
+	})
+	return resultOf[bool](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) DeleteById(ctx context.Context, id interface{}) (bool, error) {
+	inv := &Invocation{Operation: OpDeleteById, IDs: []interface{}{id}}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.DeleteById(ctx, firstId(inv))
+	})
+	return resultOf[bool](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]*Entity, error) {
+	inv := &Invocation{Operation: OpFind, Filter: filter, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.FindOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.Find(ctx, inv.Filter, o...)
+	})
+	return resultOf[[]*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) (*Entity, error) {
+	inv := &Invocation{Operation: OpFindOne, Filter: filter, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.FindOneOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.FindOne(ctx, inv.Filter, o...)
+	})
+	return resultOf[*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) FindOneAndModify(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) (*Entity, error) {
+	inv := &Invocation{Operation: OpFindOneAndModify, Filter: filter, Update: update, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.FindOneAndUpdateOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.FindOneAndModify(ctx, inv.Filter, inv.Update, o...)
+	})
+	return resultOf[*Entity](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) Count(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	inv := &Invocation{Operation: OpCount, Filter: filter, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.CountOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.Count(ctx, inv.Filter, o...)
+	})
+	return resultOf[int64](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) Exist(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (bool, error) {
+	inv := &Invocation{Operation: OpExist, Filter: filter, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.CountOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.Exist(ctx, inv.Filter, o...)
+	})
+	return resultOf[bool](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) Delete(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (int64, error) {
+	inv := &Invocation{Operation: OpDelete, Filter: filter, Options: opts}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		o, err := optionsOf[*options.DeleteOptions](inv)
+		if err != nil {
+			return nil, err
+		}
+		return r.target.Delete(ctx, inv.Filter, o...)
+	})
+	return resultOf[int64](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) QueryWithPage(ctx context.Context, filter interface{}, pageable *page.Pageable) (*page.Page[Entity], error) {
+	inv := &Invocation{Operation: OpQueryWithPage, Filter: filter, Pageable: pageable}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.QueryWithPage(ctx, inv.Filter, inv.Pageable)
+	})
+	return resultOf[*page.Page[Entity]](inv, err)
+}
+
+func (r *interceptedRepository[Entity]) QueryWithSort(ctx context.Context, filter interface{}, sort *sort.Sort) ([]*Entity, error) {
+	inv := &Invocation{Operation: OpQueryWithSort, Filter: filter, Sort: sort}
+	err := r.invoke(ctx, inv, func(ctx context.Context, inv *Invocation) (interface{}, error) {
+		return r.target.QueryWithSort(ctx, inv.Filter, inv.Sort)
+	})
+	return resultOf[[]*Entity](inv, err)
+}
+
+func firstId(inv *Invocation) interface{} {
+	if len(inv.IDs) == 0 {
+		return nil
+	}
+	return inv.IDs[0]
+}
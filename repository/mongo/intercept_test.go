@@ -0,0 +1,73 @@
+package mongo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aomi-go/data/repository/memory"
+	"github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type item struct {
+	ID   string `bson:"_id,omitempty"`
+	Name string `bson:"name"`
+}
+
+func TestIntercept(t *testing.T) {
+	ctx := context.TODO()
+	var calls []string
+	denied := errors.New("denied")
+
+	logging := func(ctx context.Context, inv *mongo.Invocation, next mongo.Handler) error {
+		calls = append(calls, "log:"+inv.Operation+":"+inv.EntityType.Name())
+		return next(ctx, inv)
+	}
+	access := func(ctx context.Context, inv *mongo.Invocation, next mongo.Handler) error {
+		if inv.Operation == mongo.OpDelete {
+			return denied
+		}
+		if inv.Operation == mongo.OpFind {
+			// 追加过滤条件
+			inv.Filter = bson.M{"name": "b"}
+		}
+		return next(ctx, inv)
+	}
+	cached := func(ctx context.Context, inv *mongo.Invocation, next mongo.Handler) error {
+		if inv.Operation == mongo.OpCount {
+			inv.Result = int64(42)
+			return nil
+		}
+		return next(ctx, inv)
+	}
+
+	repo := mongo.Intercept[item](memory.NewRepository[item](), logging, access, cached)
+	if _, err := repo.SaveMany(ctx, []*item{{Name: "a"}, {Name: "b"}}); err != nil {
+		t.Fatalf("SaveMany() error = %v", err)
+	}
+
+	found, err := repo.Find(ctx, bson.M{})
+	if err != nil || len(found) != 1 || found[0].Name != "b" {
+		t.Errorf("Find() = %v, %v", found, err)
+	}
+	if count, _ := repo.Count(ctx, bson.M{}); count != 42 {
+		t.Errorf("Count() = %d, want short-circuited 42", count)
+	}
+	if _, err := repo.Delete(ctx, bson.M{}); !errors.Is(err, denied) {
+		t.Errorf("Delete() error = %v, want denied", err)
+	}
+	if ok, _ := repo.ExistsById(ctx, found[0].ID); !ok {
+		t.Errorf("ExistsById() = false")
+	}
+
+	want := []string{"log:SaveMany:item", "log:Find:item", "log:Count:item", "log:Delete:item", "log:ExistsById:item"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v", calls)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("calls[%d] = %s, want %s", i, calls[i], want[i])
+		}
+	}
+}
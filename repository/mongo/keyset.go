@@ -0,0 +1,207 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aomi-go/data/common/page"
+	sort2 "github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/mongo/mongoxcodec"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCursorSecretRequired 未通过 SetCursorSecret 设置游标签名密钥
+var ErrCursorSecretRequired = errors.New("mongo: cursor secret is not set")
+
+// keysetRegistry 读取排序值使用的编解码器，与 matcher 保持一致
+var keysetRegistry = mongoxcodec.NewRegistry()
+
+// SetCursorSecret 设置 QueryWithCursor 游标的签名密钥，多实例部署时需要使用相同的密钥
+func (d *DocumentRepository[Entity]) SetCursorSecret(secret []byte) *DocumentRepository[Entity] {
+	d.cursorCodec = page.NewCursorCodec(secret)
+	return d
+}
+
+// keysetToken 游标内容：排序键及最后一条（向前翻页时为第一条）数据的排序值
+type keysetToken struct {
+	Keys     bson.D `bson:"k"`
+	Backward bool   `bson:"b"`
+	Values   bson.A `bson:"v"`
+}
+
+// QueryWithCursor 按排序键分页（keyset pagination），排序总是以 _id 作为最后的排序键。
+// 与 QueryWithPage 不同，不使用 skip 与 CountDocuments，翻页期间插入的数据不会导致结果重复或遗漏。
+// 排序字段的值需要为同一类型且不为 null
+func (d *DocumentRepository[Entity]) QueryWithCursor(ctx context.Context, filter interface{}, pageable *page.CursorPageable) (*page.CursorPage[Entity], error) {
+	if nil == d.cursorCodec {
+		return nil, ErrCursorSecretRequired
+	}
+	if nil == pageable {
+		pageable = &page.CursorPageable{}
+	}
+	size := pageable.GetSize()
+	keys := keysetKeys(pageable.Sort)
+
+	var token *keysetToken
+	if "" != pageable.Cursor {
+		t, err := d.decodeKeyset(pageable.Cursor, keys)
+		if err != nil {
+			return nil, err
+		}
+		token = t
+		filter = andFilter(filter, keysetFilter(keys, t.Values, t.Backward))
+	}
+	backward := nil != token && token.Backward
+
+	sort := keys
+	if backward {
+		sort = reverseKeys(keys)
+	}
+	entities, err := d.Find(ctx, filter, options.Find().SetSort(sort).SetLimit(int64(size)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	more := len(entities) > size
+	if more {
+		entities = entities[:size]
+	}
+	if backward {
+		for i, j := 0, len(entities)-1; i < j; i, j = i+1, j-1 {
+			entities[i], entities[j] = entities[j], entities[i]
+		}
+	}
+	hasNext, hasPrevious := more, nil != token
+	if backward {
+		hasNext, hasPrevious = true, more
+	}
+	if len(entities) == 0 {
+		// 越过末尾（或开头）时返回反方向的游标，便于回到上一页
+		if nil == token {
+			return page.NewCursorPage[Entity](entities, size, "", ""), nil
+		}
+		reverse, err := d.encodeValues(keys, token.Values, !backward)
+		if err != nil {
+			return nil, err
+		}
+		if backward {
+			return page.NewCursorPage[Entity](entities, size, reverse, ""), nil
+		}
+		return page.NewCursorPage[Entity](entities, size, "", reverse), nil
+	}
+
+	var next, previous string
+	if hasNext {
+		if next, err = d.encodeKeyset(keys, entities[len(entities)-1], false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrevious {
+		if previous, err = d.encodeKeyset(keys, entities[0], true); err != nil {
+			return nil, err
+		}
+	}
+	return page.NewCursorPage[Entity](entities, size, next, previous), nil
+}
+
+// keysetKeys 排序键，未包含 _id 时追加 _id 升序
+func keysetKeys(s sort2.Sort) bson.D {
+	keys := bson.D{}
+	hasId := false
+	for _, e := range GetSortOpts(s).Sort.(bson.D) {
+		keys = append(keys, e)
+		hasId = hasId || e.Key == "_id"
+	}
+	if !hasId {
+		keys = append(keys, bson.E{Key: "_id", Value: 1})
+	}
+	return keys
+}
+
+func reverseKeys(keys bson.D) bson.D {
+	reversed := make(bson.D, len(keys))
+	for i, e := range keys {
+		reversed[i] = bson.E{Key: e.Key, Value: -e.Value.(int)}
+	}
+	return reversed
+}
+
+// keysetFilter 位于 values 之后（backward 时为之前）的数据：
+// (k1 > v1) or (k1 = v1 and k2 > v2) or ...，降序字段使用 $lt
+func keysetFilter(keys bson.D, values bson.A, backward bool) bson.M {
+	or := bson.A{}
+	for i, e := range keys {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[keys[j].Key] = values[j]
+		}
+		op := "$gt"
+		if (e.Value.(int) < 0) != backward {
+			op = "$lt"
+		}
+		clause[e.Key] = bson.M{op: values[i]}
+		or = append(or, clause)
+	}
+	return bson.M{"$or": or}
+}
+
+func (d *DocumentRepository[Entity]) encodeKeyset(keys bson.D, entity *Entity, backward bool) (string, error) {
+	doc, err := bson.MarshalWithRegistry(keysetRegistry, entity)
+	if err != nil {
+		return "", err
+	}
+	values := make(bson.A, len(keys))
+	for i, e := range keys {
+		// 缺失的字段按 null 处理
+		if v, err := bson.Raw(doc).LookupErr(strings.Split(e.Key, ".")...); nil == err {
+			if err := v.Unmarshal(&values[i]); err != nil {
+				return "", err
+			}
+		}
+	}
+	return d.encodeValues(keys, values, backward)
+}
+
+func (d *DocumentRepository[Entity]) encodeValues(keys bson.D, values bson.A, backward bool) (string, error) {
+	payload, err := bson.Marshal(keysetToken{Keys: keys, Backward: backward, Values: values})
+	if err != nil {
+		return "", err
+	}
+	return d.cursorCodec.Encode(payload), nil
+}
+
+// decodeKeyset 解码游标，游标的排序键与本次请求不一致时返回 page.ErrInvalidCursor
+func (d *DocumentRepository[Entity]) decodeKeyset(cursor string, keys bson.D) (*keysetToken, error) {
+	payload, err := d.cursorCodec.Decode(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var token keysetToken
+	if err := bson.Unmarshal(payload, &token); err != nil {
+		return nil, page.ErrInvalidCursor
+	}
+	if len(token.Keys) != len(keys) || len(token.Values) != len(keys) {
+		return nil, page.ErrInvalidCursor
+	}
+	for i, e := range keys {
+		if token.Keys[i].Key != e.Key || direction(token.Keys[i].Value) != e.Value.(int) {
+			return nil, page.ErrInvalidCursor
+		}
+	}
+	return &token, nil
+}
+
+// direction 解码后的排序方向，bson 会将 int 编码为 int32
+func direction(v interface{}) int {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
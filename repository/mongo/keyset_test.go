@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aomi-go/data/common/page"
+	sort2 "github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type rankedUser struct {
+	ID    mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Score int                      `bson:"score"`
+}
+
+func TestKeysetFilter(t *testing.T) {
+	var docs []bson.M
+	for _, score := range []int32{3, 1, 2, 3, 1, 2, 3} {
+		docs = append(docs, bson.M{"_id": primitive.NewObjectID(), "score": score})
+	}
+	keys := keysetKeys(sort2.NewSortBy(sort2.DESC, "score"))
+	if len(keys) != 2 || keys[1].Key != "_id" {
+		t.Fatalf("keysetKeys() = %v", keys)
+	}
+
+	// 按排序键排序后逐个作为游标位置，之后的数据应与过滤结果一致
+	sorted := append([]bson.M{}, docs...)
+	sort.Slice(sorted, func(i, j int) bool { return less(keys, sorted[i], sorted[j]) })
+	for i, at := range sorted {
+		values := bson.A{at["score"], at["_id"]}
+		for _, backward := range []bool{false, true} {
+			want := sorted[i+1:]
+			if backward {
+				want = sorted[:i]
+			}
+			m, err := matcher.New(keysetFilter(keys, values, backward))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []bson.M
+			for _, doc := range sorted {
+				if ok, _ := m.Match(doc); ok {
+					got = append(got, doc)
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("keysetFilter(%v, backward=%v) matched %d, want %d", values, backward, len(got), len(want))
+			}
+			for j := range got {
+				if got[j]["_id"] != want[j]["_id"] {
+					t.Fatalf("keysetFilter(%v, backward=%v) = %v, want %v", values, backward, got, want)
+				}
+			}
+		}
+	}
+}
+
+func less(keys bson.D, a, b bson.M) bool {
+	for _, e := range keys {
+		c := matcher.Compare(a[e.Key], b[e.Key])
+		if c != 0 {
+			return (c < 0) == (e.Value.(int) > 0)
+		}
+	}
+	return false
+}
+
+func TestKeysetToken(t *testing.T) {
+	repo := (&DocumentRepository[rankedUser]{}).SetCursorSecret([]byte("secret"))
+	keys := keysetKeys(sort2.NewSortBy(sort2.DESC, "score"))
+	u := &rankedUser{ID: mongoxentity.StrObjectId(primitive.NewObjectID().Hex()), Score: 7}
+
+	cursor, err := repo.encodeKeyset(keys, u, true)
+	if err != nil {
+		t.Fatalf("encodeKeyset() error = %v", err)
+	}
+	token, err := repo.decodeKeyset(cursor, keys)
+	if err != nil {
+		t.Fatalf("decodeKeyset() error = %v", err)
+	}
+	if !token.Backward || token.Values[0] != int32(7) || token.Values[1] != u.ID.ObjectId() {
+		t.Fatalf("decodeKeyset() = %+v", token)
+	}
+
+	for name, c := range map[string]string{
+		"tampered":   cursor[:len(cursor)-2] + "AA",
+		"malformed":  "abc",
+		"other key":  mustEncode(t, (&DocumentRepository[rankedUser]{}).SetCursorSecret([]byte("other")), keys, u),
+		"other sort": mustEncode(t, repo, keysetKeys(sort2.NewSortBy(sort2.ASC, "score")), u),
+	} {
+		if _, err := repo.decodeKeyset(c, keys); !errors.Is(err, page.ErrInvalidCursor) {
+			t.Errorf("decodeKeyset(%s) error = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+func mustEncode(t *testing.T, repo *DocumentRepository[rankedUser], keys bson.D, u *rankedUser) string {
+	c, err := repo.encodeKeyset(keys, u, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
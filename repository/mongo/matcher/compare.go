@@ -0,0 +1,196 @@
+package matcher
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// typeOrder 返回 MongoDB 的 BSON 类型比较顺序
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return 0
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.M, bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime, time.Time:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.MaxKey:
+		return 13
+	}
+	return 12
+}
+
+// Compare 按 MongoDB 的排序规则比较两个已归一化的 BSON 值，返回 -1、0 或 1
+func Compare(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return cmpInt(ta, tb)
+	}
+	switch av := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		return toFloat(av).Cmp(toFloat(b))
+	case string:
+		return strings.Compare(av, toString(b))
+	case primitive.Symbol:
+		return strings.Compare(string(av), toString(b))
+	case primitive.ObjectID:
+		bv := b.(primitive.ObjectID)
+		return bytes.Compare(av[:], bv[:])
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		}
+		if !av {
+			return -1
+		}
+		return 1
+	case primitive.DateTime, time.Time:
+		return cmpInt64(toMillis(av), toMillis(b))
+	case primitive.Timestamp:
+		bv := b.(primitive.Timestamp)
+		return primitive.CompareTimestamp(av, bv)
+	case primitive.Binary:
+		bv := b.(primitive.Binary)
+		if len(av.Data) != len(bv.Data) {
+			return cmpInt(len(av.Data), len(bv.Data))
+		}
+		if av.Subtype != bv.Subtype {
+			return cmpInt(int(av.Subtype), int(bv.Subtype))
+		}
+		return bytes.Compare(av.Data, bv.Data)
+	case bson.A:
+		bv := b.(bson.A)
+		for i := 0; i < len(av) && i < len(bv); i++ {
+			if c := Compare(av[i], bv[i]); c != 0 {
+				return c
+			}
+		}
+		return cmpInt(len(av), len(bv))
+	case bson.D:
+		bv, ok := b.(bson.D)
+		if !ok {
+			am, _ := ToM(av)
+			return Compare(am, b)
+		}
+		return compareDocuments(av, bv)
+	case bson.M:
+		bv, ok := ToM(b)
+		if !ok {
+			return 0
+		}
+		if len(av) != len(bv) {
+			return cmpInt(len(av), len(bv))
+		}
+		for k, v := range av {
+			other, ok := bv[k]
+			if !ok {
+				return 1
+			}
+			if c := Compare(v, other); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case primitive.Regex:
+		bv := b.(primitive.Regex)
+		if c := strings.Compare(av.Pattern, bv.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(av.Options, bv.Options)
+	}
+	return 0
+}
+
+// compareDocuments 与服务端一致按字段顺序逐个比较：先比较值的类型，再比较字段名，最后比较值，字段较少的文档较小
+func compareDocuments(a, b bson.D) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := cmpInt(typeOrder(a[i].Value), typeOrder(b[i].Value)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a[i].Key, b[i].Key); c != 0 {
+			return c
+		}
+		if c := Compare(a[i].Value, b[i].Value); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+func toFloat(v interface{}) *big.Float {
+	switch n := v.(type) {
+	case int32:
+		return new(big.Float).SetInt64(int64(n))
+	case int64:
+		return new(big.Float).SetInt64(n)
+	case float64:
+		if math.IsNaN(n) {
+			return new(big.Float).SetInf(true)
+		}
+		return new(big.Float).SetFloat64(n)
+	case primitive.Decimal128:
+		if f, ok := new(big.Float).SetString(n.String()); ok {
+			return f
+		}
+	}
+	return new(big.Float)
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case primitive.Symbol:
+		return string(s)
+	}
+	return ""
+}
+
+func toMillis(v interface{}) int64 {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return int64(t)
+	case time.Time:
+		return t.UnixMilli()
+	}
+	return 0
+}
+
+func cmpInt(a, b int) int {
+	return cmpInt64(int64(a), int64(b))
+}
+
+func cmpInt64(a, b int64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,494 @@
+// Package matcher 在内存中对文档求值 MongoDB 风格的过滤条件，行为与服务端保持一致，
+// 可用于测试替身与客户端过滤。
+//
+// 支持的操作符：$eq $ne $gt $gte $lt $lte $in $nin $exists $regex $options $not $and $or $nor $size，
+// 支持点号路径、数组下标以及数组字段的元素匹配。与服务端一致，内嵌文档的相等比较要求字段顺序相同。
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Matcher 编译后的过滤条件
+type Matcher struct {
+	registry *bsoncodec.Registry
+	match    predicate
+}
+
+type predicate func(doc bson.M) bool
+
+// Match 判断 doc 是否满足 filter，filter 与 doc 均可为 struct、map、bson.D、bson.Raw 等
+func Match(filter interface{}, doc interface{}) (bool, error) {
+	m, err := New(filter)
+	if err != nil {
+		return false, err
+	}
+	return m.Match(doc)
+}
+
+// New 编译过滤条件，例如 QueryBuilder.Build() 的结果
+func New(filter interface{}) (*Matcher, error) {
+	return NewWithRegistry(nil, filter)
+}
+
+// NewWithRegistry 使用指定的 Registry 编译过滤条件，registry 应与 mongo.Client 使用的保持一致
+func NewWithRegistry(registry *bsoncodec.Registry, filter interface{}) (*Matcher, error) {
+	doc, err := Normalize(registry, filter)
+	if err != nil {
+		return nil, err
+	}
+	p, err := compileDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Matcher{registry: registry, match: p}, nil
+}
+
+// Match 判断文档是否满足条件
+func (m *Matcher) Match(doc interface{}) (bool, error) {
+	normalized, err := Normalize(m.registry, doc)
+	if err != nil {
+		return false, err
+	}
+	return m.match(normalized), nil
+}
+
+// MatchDocument 判断已归一化（见 Normalize）的文档是否满足条件
+func (m *Matcher) MatchDocument(doc bson.M) bool {
+	return m.match(doc)
+}
+
+// Lookup 按点号路径获取文档中的值，支持数组下标
+func Lookup(doc bson.M, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case bson.M:
+			next, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case bson.D:
+			next, ok := field(v, part)
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case bson.A:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// field 返回有序文档中 key 的值
+func field(d bson.D, key string) (interface{}, bool) {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func compileDocument(filter bson.M) (predicate, error) {
+	var predicates []predicate
+	for key, value := range filter {
+		var p predicate
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			p, err = compileLogical(key, value)
+		case "$comment":
+			continue
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("matcher: unsupported top level operator %s", key)
+			}
+			p, err = compileField(key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+	return allOf(predicates), nil
+}
+
+func compileLogical(op string, value interface{}) (predicate, error) {
+	arr, ok := value.(bson.A)
+	if !ok || len(arr) == 0 {
+		return nil, fmt.Errorf("matcher: %s requires a non-empty array", op)
+	}
+	predicates := make([]predicate, 0, len(arr))
+	for _, item := range arr {
+		doc, ok := ToM(item)
+		if !ok {
+			return nil, fmt.Errorf("matcher: %s entries must be documents", op)
+		}
+		p, err := compileDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+
+	switch op {
+	case "$and":
+		return allOf(predicates), nil
+	case "$or":
+		return anyOf(predicates), nil
+	default:
+		p := anyOf(predicates)
+		return func(doc bson.M) bool { return !p(doc) }, nil
+	}
+}
+
+func allOf(predicates []predicate) predicate {
+	return func(doc bson.M) bool {
+		for _, p := range predicates {
+			if !p(doc) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func anyOf(predicates []predicate) predicate {
+	return func(doc bson.M) bool {
+		for _, p := range predicates {
+			if p(doc) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// valuePredicate 对字段解析出的值求值，values 为路径匹配到的所有值，为空表示字段不存在
+type valuePredicate func(values []interface{}) bool
+
+func compileField(path string, value interface{}) (predicate, error) {
+	var vp valuePredicate
+	var err error
+	if m, ok := ToM(value); ok && isOperatorDocument(m) {
+		vp, err = compileOperators(m)
+	} else if re, ok := value.(primitive.Regex); ok {
+		vp, err = regexPredicate(re.Pattern, re.Options)
+	} else {
+		vp = eqPredicate(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("matcher: field %s: %w", path, err)
+	}
+
+	parts := strings.Split(path, ".")
+	return func(doc bson.M) bool {
+		return vp(resolve(doc, parts))
+	}, nil
+}
+
+func isOperatorDocument(m bson.M) bool {
+	for k := range m {
+		if strings.HasPrefix(k, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+func compileOperators(ops bson.M) (valuePredicate, error) {
+	var predicates []valuePredicate
+	for op, arg := range ops {
+		var p valuePredicate
+		var err error
+		switch op {
+		case "$eq":
+			p = eqPredicate(arg)
+		case "$ne":
+			eq := eqPredicate(arg)
+			p = func(values []interface{}) bool { return !eq(values) }
+		case "$gt", "$gte", "$lt", "$lte":
+			p = rangePredicate(op, arg)
+		case "$in":
+			p, err = inPredicate(arg)
+		case "$nin":
+			var in valuePredicate
+			in, err = inPredicate(arg)
+			p = func(values []interface{}) bool { return !in(values) }
+		case "$exists":
+			want := truthy(arg)
+			p = func(values []interface{}) bool { return (len(values) > 0) == want }
+		case "$regex":
+			options, _ := ops["$options"].(string)
+			switch re := arg.(type) {
+			case primitive.Regex:
+				if options == "" {
+					options = re.Options
+				}
+				p, err = regexPredicate(re.Pattern, options)
+			case string:
+				p, err = regexPredicate(re, options)
+			default:
+				err = fmt.Errorf("$regex requires a string or regex")
+			}
+		case "$options":
+			if _, ok := ops["$regex"]; !ok {
+				return nil, fmt.Errorf("$options requires $regex")
+			}
+			continue
+		case "$not":
+			p, err = notPredicate(arg)
+		case "$size":
+			p, err = sizePredicate(arg)
+		default:
+			err = fmt.Errorf("unsupported operator %s", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, p)
+	}
+
+	return func(values []interface{}) bool {
+		for _, p := range predicates {
+			if !p(values) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// resolve 按路径解析出所有匹配的值，遇到数组时会继续在每个元素中查找
+func resolve(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch t := v.(type) {
+	case bson.M:
+		next, ok := t[parts[0]]
+		if !ok {
+			return nil
+		}
+		return resolve(next, parts[1:])
+	case bson.D:
+		next, ok := field(t, parts[0])
+		if !ok {
+			return nil
+		}
+		return resolve(next, parts[1:])
+	case bson.A:
+		var out []interface{}
+		if idx, err := strconv.Atoi(parts[0]); err == nil && idx >= 0 && idx < len(t) {
+			out = append(out, resolve(t[idx], parts[1:])...)
+		}
+		for _, item := range t {
+			switch item.(type) {
+			case bson.M, bson.D:
+				out = append(out, resolve(item, parts)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// candidates 展开数组：数组本身与其元素都参与比较
+func candidates(values []interface{}) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+		if arr, ok := v.(bson.A); ok {
+			out = append(out, arr...)
+		}
+	}
+	return out
+}
+
+func eqPredicate(expected interface{}) valuePredicate {
+	isNull := typeOrder(expected) == 1
+	return func(values []interface{}) bool {
+		if len(values) == 0 {
+			return isNull
+		}
+		for _, c := range candidates(values) {
+			if typeOrder(c) == typeOrder(expected) && Compare(c, expected) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func rangePredicate(op string, bound interface{}) valuePredicate {
+	if typeOrder(bound) == 1 && (op == "$gte" || op == "$lte") {
+		return eqPredicate(bound)
+	}
+	return func(values []interface{}) bool {
+		for _, c := range candidates(values) {
+			// 与服务端一致，只在同一类型之间比较
+			if typeOrder(c) != typeOrder(bound) {
+				continue
+			}
+			r := Compare(c, bound)
+			switch op {
+			case "$gt":
+				if r > 0 {
+					return true
+				}
+			case "$gte":
+				if r >= 0 {
+					return true
+				}
+			case "$lt":
+				if r < 0 {
+					return true
+				}
+			case "$lte":
+				if r <= 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+func inPredicate(arg interface{}) (valuePredicate, error) {
+	arr, ok := arg.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("$in/$nin requires an array")
+	}
+	predicates := make([]valuePredicate, 0, len(arr))
+	for _, item := range arr {
+		if re, ok := item.(primitive.Regex); ok {
+			p, err := regexPredicate(re.Pattern, re.Options)
+			if err != nil {
+				return nil, err
+			}
+			predicates = append(predicates, p)
+		} else {
+			predicates = append(predicates, eqPredicate(item))
+		}
+	}
+	return func(values []interface{}) bool {
+		for _, p := range predicates {
+			if p(values) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func regexPredicate(pattern string, options string) (valuePredicate, error) {
+	flags := ""
+	for _, o := range options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		case 'u':
+		default:
+			return nil, fmt.Errorf("unsupported regex option %c", o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return func(values []interface{}) bool {
+		for _, c := range candidates(values) {
+			switch s := c.(type) {
+			case string:
+				if re.MatchString(s) {
+					return true
+				}
+			case primitive.Symbol:
+				if re.MatchString(string(s)) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+func notPredicate(arg interface{}) (valuePredicate, error) {
+	var inner valuePredicate
+	var err error
+	switch v := arg.(type) {
+	case bson.M, bson.D:
+		m, _ := ToM(v)
+		if !isOperatorDocument(m) {
+			return nil, fmt.Errorf("$not requires an operator document or regex")
+		}
+		inner, err = compileOperators(m)
+	case primitive.Regex:
+		inner, err = regexPredicate(v.Pattern, v.Options)
+	default:
+		return nil, fmt.Errorf("$not requires an operator document or regex")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(values []interface{}) bool { return !inner(values) }, nil
+}
+
+func sizePredicate(arg interface{}) (valuePredicate, error) {
+	size, ok := toInt64(arg)
+	if !ok {
+		return nil, fmt.Errorf("$size requires a number")
+	}
+	return func(values []interface{}) bool {
+		for _, v := range values {
+			if arr, ok := v.(bson.A); ok && int64(len(arr)) == size {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case nil, primitive.Null:
+		return false
+	}
+	if n, ok := toInt64(v); ok {
+		return n != 0
+	}
+	return true
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	mongox "github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type address struct {
+	City string `bson:"city"`
+}
+
+type user struct {
+	ID        mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Name      string                   `bson:"name"`
+	Age       int                      `bson:"age"`
+	Tags      []string                 `bson:"tags"`
+	Addresses []address                `bson:"addresses"`
+	Nickname  *string                  `bson:"nickname,omitempty"`
+}
+
+func TestMatch(t *testing.T) {
+	id := mongoxentity.NewStrObjectId()
+	doc := user{
+		ID:        id,
+		Name:      "Alice",
+		Age:       30,
+		Tags:      []string{"admin", "dev"},
+		Addresses: []address{{City: "Shanghai"}, {City: "Beijing"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		want   bool
+	}{
+		{"empty", bson.M{}, true},
+		{"eq", bson.M{"name": "Alice"}, true},
+		{"eq object id", bson.M{"_id": id.ObjectId()}, true},
+		{"eq str object id", bson.M{"_id": id}, true},
+		{"eq numeric types", bson.M{"age": 30.0}, true},
+		{"ne", bson.M{"name": bson.M{"$ne": "Alice"}}, false},
+		{"gt", bson.M{"age": bson.M{"$gt": 29}}, true},
+		{"gte lte", bson.M{"age": bson.M{"$gte": 30, "$lte": 30}}, true},
+		{"lt", bson.M{"age": bson.M{"$lt": 30}}, false},
+		{"cross type range", bson.M{"age": bson.M{"$gt": "10"}}, false},
+		{"in", bson.M{"name": bson.M{"$in": []interface{}{"Bob", "Alice"}}}, true},
+		{"nin", bson.M{"name": bson.M{"$nin": []interface{}{"Bob", "Alice"}}}, false},
+		{"array contains", bson.M{"tags": "dev"}, true},
+		{"array in", bson.M{"tags": bson.M{"$in": []interface{}{"ops", "admin"}}}, true},
+		{"array whole", bson.M{"tags": []string{"admin", "dev"}}, true},
+		{"array index", bson.M{"tags.1": "dev"}, true},
+		{"dotted array of docs", bson.M{"addresses.city": "Beijing"}, true},
+		{"dotted missing", bson.M{"addresses.city": "Hangzhou"}, false},
+		{"exists", bson.M{"nickname": bson.M{"$exists": false}}, true},
+		{"exists true", bson.M{"name": bson.M{"$exists": true}}, true},
+		{"eq null matches missing", bson.M{"nickname": nil}, true},
+		{"ne on missing", bson.M{"nickname": bson.M{"$ne": "x"}}, true},
+		{"regex", bson.M{"name": bson.M{"$regex": primitive.Regex{Pattern: "^al", Options: "i"}}}, true},
+		{"regex string options", bson.M{"name": bson.M{"$regex": "^AL", "$options": "i"}}, true},
+		{"regex value", bson.M{"name": primitive.Regex{Pattern: "ice$"}}, true},
+		{"not", bson.M{"age": bson.M{"$not": bson.M{"$gt": 40}}}, true},
+		{"not regex", bson.M{"name": bson.M{"$not": primitive.Regex{Pattern: "^A"}}}, false},
+		{"and", bson.M{"$and": []interface{}{bson.M{"name": "Alice"}, bson.M{"age": 31}}}, false},
+		{"or", bson.M{"$or": []interface{}{bson.M{"name": "Bob"}, bson.M{"age": 30}}}, true},
+		{"nor", bson.M{"$nor": []interface{}{bson.M{"name": "Bob"}}}, true},
+		{"size", bson.M{"tags": bson.M{"$size": 2}}, true},
+		{"query builder", mongox.NewQueryBuilder().Like("name", "lic").Between("age", 18, 40).In("tags", "dev").Build(), true},
+		{"query builder miss", mongox.NewQueryBuilder().Like("name", "bob").Gt("age", 18).Build(), false},
+		{"bson.D filter", bson.D{{Key: "name", Value: "Alice"}, {Key: "age", Value: 30}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(tt.filter, doc)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedOperator(t *testing.T) {
+	if _, err := New(bson.M{"age": bson.M{"$where": "x"}}); err == nil {
+		t.Errorf("New() expected error for unsupported operator")
+	}
+	if _, err := New(bson.M{"$expr": bson.M{}}); err == nil {
+		t.Errorf("New() expected error for unsupported top level operator")
+	}
+}
+
+func TestEmbeddedDocumentOrder(t *testing.T) {
+	doc := bson.D{{Key: "profile", Value: bson.D{{Key: "city", Value: "Shanghai"}, {Key: "zip", Value: "200000"}}}}
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		want   bool
+	}{
+		{"same order", bson.M{"profile": bson.D{{Key: "city", Value: "Shanghai"}, {Key: "zip", Value: "200000"}}}, true},
+		{"different order", bson.M{"profile": bson.D{{Key: "zip", Value: "200000"}, {Key: "city", Value: "Shanghai"}}}, false},
+		{"subset", bson.M{"profile": bson.D{{Key: "city", Value: "Shanghai"}}}, false},
+		{"dotted", bson.M{"profile.zip": "200000"}, true},
+		{"in", bson.M{"profile": bson.M{"$in": bson.A{bson.D{{Key: "city", Value: "Shanghai"}, {Key: "zip", Value: "200000"}}}}}, true},
+		{"gt fewer fields", bson.M{"profile": bson.M{"$gt": bson.D{{Key: "city", Value: "Shanghai"}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(tt.filter, doc)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/aomi-go/data/mongo/mongoxcodec"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+var defaultRegistry = mongoxcodec.NewRegistry()
+
+// Normalize 使用 registry 将任意文档（struct、map、bson.D、bson.Raw 等）编码后再解码为 bson.M，
+// 嵌套文档为 bson.D（保留字段顺序），数组为 bson.A，与服务端看到的 BSON 类型保持一致。registry 为 nil 时使用 mongoxcodec.NewRegistry
+func Normalize(registry *bsoncodec.Registry, v interface{}) (bson.M, error) {
+	if isNil(v) {
+		return bson.M{}, nil
+	}
+	if nil == registry {
+		registry = defaultRegistry
+	}
+
+	buf := new(bytes.Buffer)
+	vw, err := bsonrw.NewBSONValueWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := bson.NewEncoder(vw)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.SetRegistry(registry); err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(buf.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if err := dec.SetRegistry(registry); err != nil {
+		return nil, err
+	}
+
+	var ordered bson.D
+	if err := dec.Decode(&ordered); err != nil {
+		return nil, err
+	}
+	doc := make(bson.M, len(ordered))
+	for _, e := range ordered {
+		doc[e.Key] = e.Value
+	}
+	return doc, nil
+}
+
+// ToM 将文档转换为 bson.M，用于操作符文档等与字段顺序无关的场景，v 不是文档时返回 false
+func ToM(v interface{}) (bson.M, bool) {
+	switch d := v.(type) {
+	case bson.M:
+		return d, true
+	case bson.D:
+		m := make(bson.M, len(d))
+		for _, e := range d {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Ptr, reflect.Interface, reflect.Slice:
+		return rv.IsNil()
+	}
+	return false
+}
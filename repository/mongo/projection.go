@@ -0,0 +1,145 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/page"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var projections sync.Map
+
+// ProjectionOf 根据 DTO 的 bson 标签生成投影：只返回 DTO 中的字段，inline 结构体平铺，DTO 没有 _id 字段时排除 _id
+func ProjectionOf[DTO interface{}]() (bson.D, error) {
+	t := reflect.TypeOf((*DTO)(nil)).Elem()
+	if v, ok := projections.Load(t); ok {
+		return v.(bson.D), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mongo: projection type must be a struct, got %s", t)
+	}
+	projection := bson.D{}
+	if err := collectProjection(t, &projection); err != nil {
+		return nil, err
+	}
+	hasId := false
+	for _, e := range projection {
+		hasId = hasId || e.Key == "_id"
+	}
+	if !hasId {
+		projection = append(projection, bson.E{Key: "_id", Value: 0})
+	}
+	projections.Store(t, projection)
+	return projection, nil
+}
+
+func collectProjection(t reflect.Type, projection *bson.D) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := bsoncodec.DefaultStructTagParser.ParseStructTags(sf)
+		if err != nil {
+			return err
+		}
+		if tags.Skip {
+			continue
+		}
+		if tags.Inline {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := collectProjection(ft, projection); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		*projection = append(*projection, bson.E{Key: tags.Name, Value: 1})
+	}
+	return nil
+}
+
+// projectedOptions 调用方未设置投影时追加 DTO 的投影
+func projectedOptions[DTO interface{}](opts []*options.FindOptions) ([]*options.FindOptions, error) {
+	for _, opt := range opts {
+		if nil != opt && nil != opt.Projection {
+			return opts, nil
+		}
+	}
+	projection, err := ProjectionOf[DTO]()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]*options.FindOptions{}, opts...), options.Find().SetProjection(projection)), nil
+}
+
+// FindProjected 按 DTO 的字段投影查询并直接解码为 DTO，过滤条件与 repo.Find 一样受租户、软删除限定
+func FindProjected[Entity interface{}, DTO interface{}](ctx context.Context, repo *DocumentRepository[Entity], filter interface{}, opts ...*options.FindOptions) ([]*DTO, error) {
+	projected, err := projectedOptions[DTO](opts)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := repo.FindWithCursor(ctx, filter, projected...)
+	if err != nil {
+		return nil, err
+	}
+	it := newIterator[DTO](ctx, cursor)
+	defer it.Close()
+
+	result := make([]*DTO, 0)
+	for it.Next() {
+		result = append(result, it.Entity())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindOneProjected 按 DTO 的字段投影查询一条数据，没有数据时返回 common.ErrNoResult
+func FindOneProjected[Entity interface{}, DTO interface{}](ctx context.Context, repo *DocumentRepository[Entity], filter interface{}, opts ...*options.FindOptions) (*DTO, error) {
+	limited := append(append([]*options.FindOptions{}, opts...), options.Find().SetLimit(1))
+	result, err := FindProjected[Entity, DTO](ctx, repo, filter, limited...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, common.ErrNoResult
+	}
+	return result[0], nil
+}
+
+// QueryWithPageProjected 按 DTO 的字段投影分页查询
+func QueryWithPageProjected[Entity interface{}, DTO interface{}](ctx context.Context, repo *DocumentRepository[Entity], filter interface{}, pageable *page.Pageable) (*page.Page[DTO], error) {
+	if nil == pageable {
+		pageable = page.NewDefaultPageable()
+	}
+	total, err := repo.Count(ctx, filter)
+	if nil != err {
+		return nil, err
+	}
+
+	if total == 0 {
+		return page.NewPage[DTO](make([]*DTO, 0), 0, pageable), nil
+	}
+
+	pageOpts := options.Find().SetSkip(pageable.GetOffset()).SetLimit(int64(pageable.GetSize()))
+	sortOpts := GetSortOpts(pageable.Sort)
+
+	dtos, err := FindProjected[Entity, DTO](ctx, repo, filter, pageOpts, sortOpts)
+	if nil != err {
+		return nil, err
+	}
+
+	return page.NewPage[DTO](dtos, total, pageable), nil
+}
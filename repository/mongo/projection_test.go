@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type userSummary struct {
+	mongoxentity.TenantFields `bson:",inline"`
+	ID                        mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Name                      string                   `bson:"name"`
+	Ignored                   string                   `bson:"-"`
+	internal                  string
+}
+
+type userName struct {
+	Name string
+}
+
+func TestProjectionOf(t *testing.T) {
+	projection, err := ProjectionOf[userSummary]()
+	if err != nil {
+		t.Fatalf("ProjectionOf() error = %v", err)
+	}
+	want := bson.D{{Key: "tenant_id", Value: 1}, {Key: "_id", Value: 1}, {Key: "name", Value: 1}}
+	if !reflect.DeepEqual(projection, want) {
+		t.Errorf("ProjectionOf() = %v, want %v", projection, want)
+	}
+
+	projection, _ = ProjectionOf[userName]()
+	want = bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}}
+	if !reflect.DeepEqual(projection, want) {
+		t.Errorf("ProjectionOf() without _id = %v, want %v", projection, want)
+	}
+
+	if _, err := ProjectionOf[string](); err == nil {
+		t.Errorf("ProjectionOf[string]() error = nil")
+	}
+
+	opts, err := projectedOptions[userName]([]*options.FindOptions{options.Find().SetLimit(1)})
+	if err != nil || len(opts) != 2 || !reflect.DeepEqual(opts[1].Projection, want) {
+		t.Fatalf("projectedOptions() = %v, %v", opts, err)
+	}
+	// 调用方设置了投影时不再追加
+	custom := []*options.FindOptions{options.Find().SetProjection(bson.D{{Key: "age", Value: 1}}), nil}
+	if opts, _ := projectedOptions[userName](custom); len(opts) != 2 || nil != opts[1] {
+		t.Errorf("projectedOptions() with projection = %v", opts)
+	}
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/tenant"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionResolver 按 context 解析每次操作使用的数据库与集合名称。
+// db、collectionName 为仓储构造时的默认值，返回的集合句柄由仓储按 (client, 数据库, 集合) 缓存
+type CollectionResolver interface {
+	Resolve(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error)
+}
+
+// CollectionResolverFunc 函数形式的 CollectionResolver
+type CollectionResolverFunc func(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error)
+
+func (f CollectionResolverFunc) Resolve(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error) {
+	return f(ctx, db, collectionName)
+}
+
+// DatabasePerTenant 每个租户使用独立的数据库，数据库名称为 prefix + 租户 ID。
+// context 中没有租户时返回 common.ErrTenantRequired，系统 context 使用默认数据库
+func DatabasePerTenant(prefix string, opts ...*options.DatabaseOptions) CollectionResolver {
+	return CollectionResolverFunc(func(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error) {
+		tenantId, ok := tenant.FromContext(ctx)
+		if !ok {
+			if tenant.IsSystem(ctx) {
+				return db, collectionName, nil
+			}
+			return nil, "", common.ErrTenantRequired
+		}
+		return db.Client().Database(prefix+tenantId, opts...), collectionName, nil
+	})
+}
+
+// CollectionPrefixPerTenant 每个租户使用独立的集合，集合名称为 租户 ID + separator + 默认集合名称。
+// context 中没有租户时返回 common.ErrTenantRequired，系统 context 使用默认集合
+func CollectionPrefixPerTenant(separator string) CollectionResolver {
+	return CollectionResolverFunc(func(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error) {
+		tenantId, ok := tenant.FromContext(ctx)
+		if !ok {
+			if tenant.IsSystem(ctx) {
+				return db, collectionName, nil
+			}
+			return nil, "", common.ErrTenantRequired
+		}
+		return db, tenantId + separator + collectionName, nil
+	})
+}
+
+// SetCollectionResolver 设置集合解析器，设置后每次操作按 context 解析数据库与集合，集合选项沿用构造时传入的 collectionOpts
+func (d *DocumentRepository[Entity]) SetCollectionResolver(resolver CollectionResolver) *DocumentRepository[Entity] {
+	d.resolver = resolver
+	d.collections = &sync.Map{}
+	return d
+}
+
+type collectionKey struct {
+	client     *mongo.Client
+	database   string
+	collection string
+}
+
+// collectionOf 返回本次操作使用的集合
+func (d *DocumentRepository[Entity]) collectionOf(ctx context.Context) (*mongo.Collection, error) {
+	if nil == d.resolver {
+		return d.collection, nil
+	}
+	db, name, err := d.resolver.Resolve(ctx, d.db, d.collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if db == d.db && name == d.collectionName {
+		return d.collection, nil
+	}
+	key := collectionKey{client: db.Client(), database: db.Name(), collection: name}
+	if c, ok := d.collections.Load(key); ok {
+		return c.(*mongo.Collection), nil
+	}
+	c, _ := d.collections.LoadOrStore(key, db.Collection(name, d.collectionOpts...))
+	return c.(*mongo.Collection), nil
+}
+
+// GetCollectionWithContext 返回 context 对应的集合，未设置集合解析器时为构造时的集合
+func (d *DocumentRepository[Entity]) GetCollectionWithContext(ctx context.Context) (*mongo.Collection, error) {
+	return d.collectionOf(ctx)
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/tenant"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCollectionResolver(t *testing.T) {
+	// Connect 不会立即建立连接，这里只解析集合句柄
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://127.0.0.1:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.TODO())
+
+	t1 := tenant.WithTenant(context.TODO(), "t1")
+	t2 := tenant.WithTenant(context.TODO(), "t2")
+
+	repo := NewDocumentRepository[User](client.Database("app"), "user").SetCollectionResolver(DatabasePerTenant("app_"))
+	c1, err := repo.collectionOf(t1)
+	if err != nil {
+		t.Fatalf("collectionOf() error = %v", err)
+	}
+	if c1.Database().Name() != "app_t1" || c1.Name() != "user" {
+		t.Errorf("collectionOf() = %s.%s", c1.Database().Name(), c1.Name())
+	}
+	if again, _ := repo.collectionOf(t1); again != c1 {
+		t.Errorf("collectionOf() did not reuse the cached handle")
+	}
+	if c2, _ := repo.collectionOf(t2); c2.Database().Name() != "app_t2" {
+		t.Errorf("collectionOf() for t2 = %s", c2.Database().Name())
+	}
+	if _, err := repo.collectionOf(context.TODO()); !errors.Is(err, common.ErrTenantRequired) {
+		t.Errorf("collectionOf() without tenant error = %v, want ErrTenantRequired", err)
+	}
+	if c, _ := repo.collectionOf(tenant.WithSystem(context.TODO())); c != repo.collection {
+		t.Errorf("collectionOf() in system context did not use the default collection")
+	}
+	// GetCollection 不按租户解析，返回默认集合
+	if c := repo.GetCollection(); c != repo.collection {
+		t.Errorf("GetCollection() with a resolver = %s.%s, want default collection", c.Database().Name(), c.Name())
+	}
+
+	repo.SetCollectionResolver(CollectionPrefixPerTenant("_"))
+	c1, err = repo.collectionOf(t1)
+	if err != nil || c1.Database().Name() != "app" || c1.Name() != "t1_user" {
+		t.Errorf("collectionOf() = %v, %v", c1, err)
+	}
+}
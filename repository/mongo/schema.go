@@ -0,0 +1,265 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ValidationLevelStrict 校验所有插入与更新
+	ValidationLevelStrict = "strict"
+	// ValidationLevelModerate 不校验已存在的不合法文档的更新
+	ValidationLevelModerate = "moderate"
+	// ValidationLevelOff 关闭校验
+	ValidationLevelOff = "off"
+
+	// ValidationActionError 拒绝不合法的写入
+	ValidationActionError = "error"
+	// ValidationActionWarn 只记录日志
+	ValidationActionWarn = "warn"
+)
+
+var (
+	decimalType     = reflect.TypeOf(decimal.Decimal{})
+	strObjectIdType = reflect.TypeOf(mongoxentity.StrObjectId(""))
+	objectIdType    = reflect.TypeOf(primitive.ObjectID{})
+	dbRefType       = reflect.TypeOf(mongoxentity.DBRef{})
+
+	// primitiveTypes 驱动按固定 BSON 类型编码的类型，不能按 Go 的 kind 推断
+	primitiveTypes = map[reflect.Type]string{
+		reflect.TypeOf(primitive.DateTime(0)):    "date",
+		reflect.TypeOf(primitive.Decimal128{}):   "decimal",
+		reflect.TypeOf(primitive.Binary{}):       "binData",
+		reflect.TypeOf(primitive.Timestamp{}):    "timestamp",
+		reflect.TypeOf(primitive.Regex{}):        "regex",
+		reflect.TypeOf(primitive.JavaScript("")): "javascript",
+		reflect.TypeOf(primitive.Symbol("")):     "symbol",
+		reflect.TypeOf(primitive.MinKey{}):       "minKey",
+		reflect.TypeOf(primitive.MaxKey{}):       "maxKey",
+		reflect.TypeOf(bson.Raw{}):               "object",
+	}
+	documentType       = reflect.TypeOf(primitive.D{})
+	valueMarshalerType = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+	marshalerType      = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+)
+
+// SchemaOf 根据实体的 bson 标签生成 $jsonSchema 校验文档：
+// 非 omitempty 且非指针的字段为必填，指针、切片、map 允许为 null，inline 结构体平铺，未声明的字段不做限制。
+// decimal.Decimal 对应 decimal，mongoxentity.StrObjectId 对应 objectId，mongoxentity.DBRef 要求包含 $ref、$id，
+// primitive 包中的类型与 [N]byte 按驱动编码的 BSON 类型生成，自行实现 bson.Marshaler、bson.ValueMarshaler 的类型不支持
+func SchemaOf[Entity interface{}]() (bson.D, error) {
+	t := reflect.TypeOf((*Entity)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mongo: schema type must be a struct, got %s", t)
+	}
+	return objectSchema(t, map[reflect.Type]bool{t: true})
+}
+
+func objectSchema(t reflect.Type, visiting map[reflect.Type]bool) (bson.D, error) {
+	properties := bson.D{}
+	var required bson.A
+	if err := collectSchema(t, visiting, &properties, &required); err != nil {
+		return nil, err
+	}
+	schema := bson.D{{Key: "bsonType", Value: "object"}}
+	if len(required) > 0 {
+		schema = append(schema, bson.E{Key: "required", Value: required})
+	}
+	if len(properties) > 0 {
+		schema = append(schema, bson.E{Key: "properties", Value: properties})
+	}
+	return schema, nil
+}
+
+func collectSchema(t reflect.Type, visiting map[reflect.Type]bool, properties *bson.D, required *bson.A) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := bsoncodec.DefaultStructTagParser.ParseStructTags(sf)
+		if err != nil {
+			return err
+		}
+		if tags.Skip {
+			continue
+		}
+		if tags.Inline {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			// inline map 的键不固定，不做限制
+			if ft.Kind() == reflect.Struct {
+				if err := collectSchema(ft, visiting, properties, required); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		schema, nullable, err := valueSchema(sf.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("mongo: field %s: %w", sf.Name, err)
+		}
+		*properties = append(*properties, bson.E{Key: tags.Name, Value: schema})
+		if !tags.OmitEmpty && !nullable {
+			*required = append(*required, tags.Name)
+		}
+	}
+	return nil
+}
+
+// valueSchema 字段类型的 schema，nullable 表示该类型的零值编码为 null
+func valueSchema(t reflect.Type, visiting map[reflect.Type]bool) (schema bson.D, nullable bool, err error) {
+	if t.Kind() == reflect.Ptr {
+		schema, _, err = valueSchema(t.Elem(), visiting)
+		return withNull(schema), true, err
+	}
+
+	switch t {
+	case decimalType:
+		return bsonType("decimal"), false, nil
+	case strObjectIdType, objectIdType:
+		return bsonType("objectId"), false, nil
+	case timeType:
+		return bsonType("date"), false, nil
+	case dbRefType:
+		return bson.D{
+			{Key: "bsonType", Value: "object"},
+			{Key: "required", Value: bson.A{"$ref", "$id"}},
+		}, false, nil
+	}
+	if name, ok := primitiveTypes[t]; ok {
+		return bsonType(name), false, nil
+	}
+	if t == documentType {
+		return withNull(bsonType("object")), true, nil
+	}
+	// 编码结果由类型自身决定，无法推断
+	if t.Implements(valueMarshalerType) || t.Implements(marshalerType) ||
+		reflect.PointerTo(t).Implements(valueMarshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return nil, false, fmt.Errorf("unsupported type %s: implements bson.Marshaler or bson.ValueMarshaler", t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return bsonType("string"), false, nil
+	case reflect.Bool:
+		return bsonType("bool"), false, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return bsonType("int"), false, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		// int 在取值范围内编码为 int32，否则为 int64
+		return bsonType("int", "long"), false, nil
+	case reflect.Float32, reflect.Float64:
+		return bsonType("double", "int", "long", "decimal"), false, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return withNull(bsonType("binData")), true, nil
+		}
+		items, _, err := valueSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, false, err
+		}
+		return withNull(append(bsonType("array"), bson.E{Key: "items", Value: items})), true, nil
+	case reflect.Array:
+		// [N]byte（如 uuid.UUID）编码为二进制
+		if t.Elem().Kind() == reflect.Uint8 {
+			return bsonType("binData"), false, nil
+		}
+		items, _, err := valueSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, false, err
+		}
+		return append(bsonType("array"), bson.E{Key: "items", Value: items}), false, nil
+	case reflect.Map:
+		values, _, err := valueSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, false, err
+		}
+		return withNull(append(bsonType("object"), bson.E{Key: "additionalProperties", Value: values})), true, nil
+	case reflect.Interface:
+		return bson.D{}, true, nil
+	case reflect.Struct:
+		// 递归引用的结构体只校验类型
+		if visiting[t] {
+			return bsonType("object"), false, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		schema, err := objectSchema(t, visiting)
+		return schema, false, err
+	}
+	return nil, false, fmt.Errorf("unsupported type %s", t)
+}
+
+func bsonType(types ...string) bson.D {
+	if len(types) == 1 {
+		return bson.D{{Key: "bsonType", Value: types[0]}}
+	}
+	a := make(bson.A, len(types))
+	for i, v := range types {
+		a[i] = v
+	}
+	return bson.D{{Key: "bsonType", Value: a}}
+}
+
+// withNull bsonType 中追加 null
+func withNull(schema bson.D) bson.D {
+	for i, e := range schema {
+		if "bsonType" != e.Key {
+			continue
+		}
+		result := append(bson.D{}, schema...)
+		switch v := e.Value.(type) {
+		case string:
+			result[i].Value = bson.A{v, "null"}
+		case bson.A:
+			result[i].Value = append(append(bson.A{}, v...), "null")
+		}
+		return result
+	}
+	return schema
+}
+
+// ApplySchemaValidator 将 SchemaOf 生成的 $jsonSchema 设置为集合的校验规则，集合不存在时创建集合。
+// level 为 ValidationLevelXxx，action 为 ValidationActionXxx。按租户路由集合时只处理 ctx 对应的集合
+func (d *DocumentRepository[Entity]) ApplySchemaValidator(ctx context.Context, level string, action string) error {
+	schema, err := SchemaOf[Entity]()
+	if err != nil {
+		return err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return err
+	}
+	validator := bson.D{{Key: "$jsonSchema", Value: schema}}
+
+	err = collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection.Name()},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: level},
+		{Key: "validationAction", Value: action},
+	}).Err()
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Name != "NamespaceNotFound" {
+		return err
+	}
+
+	opts := options.CreateCollection().
+		SetValidator(validator).
+		SetValidationLevel(level).
+		SetValidationAction(action)
+	return collection.Database().CreateCollection(ctx, collection.Name(), opts)
+}
@@ -0,0 +1,242 @@
+package mongo
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/mongo/mongoxcodec"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type schemaItem struct {
+	Sku string `bson:"sku"`
+	Qty int32  `bson:"qty"`
+}
+
+type schemaOrder struct {
+	Id                       mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	mongoxentity.AuditFields `bson:",inline"`
+	Amount                   decimal.Decimal    `bson:"amount"`
+	Paid                     bool               `bson:"paid"`
+	Count                    int                `bson:"count"`
+	Note                     *string            `bson:"note"`
+	Items                    []schemaItem       `bson:"items"`
+	Customer                 mongoxentity.DBRef `bson:"customer"`
+	Labels                   map[string]string  `bson:"labels,omitempty"`
+	Parent                   *schemaOrder       `bson:"parent,omitempty"`
+	Ignored                  string             `bson:"-"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf[schemaOrder]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := schema.Map()
+	required := map[string]bool{}
+	for _, v := range m["required"].(bson.A) {
+		required[v.(string)] = true
+	}
+	for _, name := range []string{"created_at", "updated_at", "amount", "paid", "count", "customer"} {
+		if !required[name] {
+			t.Errorf("%s should be required, required = %v", name, required)
+		}
+	}
+	for _, name := range []string{"_id", "created_by", "note", "items", "labels", "parent", "Ignored"} {
+		if required[name] {
+			t.Errorf("%s should not be required", name)
+		}
+	}
+
+	properties := m["properties"].(bson.D).Map()
+	typeOf := func(name string) interface{} {
+		return properties[name].(bson.D).Map()["bsonType"]
+	}
+	if typeOf("_id") != "objectId" || typeOf("amount") != "decimal" || typeOf("created_at") != "date" || typeOf("paid") != "bool" {
+		t.Errorf("properties = %v", properties)
+	}
+	if v, ok := typeOf("note").(bson.A); !ok || len(v) != 2 || v[0] != "string" || v[1] != "null" {
+		t.Errorf("note bsonType = %v", typeOf("note"))
+	}
+	if _, ok := properties["Ignored"]; ok {
+		t.Error("skipped field in properties")
+	}
+
+	items := properties["items"].(bson.D).Map()["items"].(bson.D).Map()
+	if items["bsonType"] != "object" || len(items["required"].(bson.A)) != 2 {
+		t.Errorf("items schema = %v", items)
+	}
+	if ref := properties["customer"].(bson.D).Map(); ref["bsonType"] != "object" || len(ref["required"].(bson.A)) != 2 {
+		t.Errorf("customer schema = %v", ref)
+	}
+	// 递归引用只校验类型
+	if _, ok := properties["parent"].(bson.D).Map()["properties"]; ok {
+		t.Errorf("parent schema = %v", properties["parent"])
+	}
+	if _, err := bson.Marshal(bson.D{{Key: "$jsonSchema", Value: schema}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type schemaSample struct {
+	Id        mongoxentity.StrObjectId `bson:"_id"`
+	Amount    decimal.Decimal          `bson:"amount"`
+	Count     int                      `bson:"count"`
+	Size      uint32                   `bson:"size"`
+	Ratio     float64                  `bson:"ratio"`
+	Uuid      [16]byte                 `bson:"uuid"`
+	Data      []byte                   `bson:"data"`
+	Date      primitive.DateTime       `bson:"date"`
+	Price     primitive.Decimal128     `bson:"price"`
+	Binary    primitive.Binary         `bson:"binary"`
+	Timestamp primitive.Timestamp      `bson:"timestamp"`
+	Regex     primitive.Regex          `bson:"regex"`
+	Doc       bson.D                   `bson:"doc"`
+	Raw       bson.Raw                 `bson:"raw"`
+	Due       time.Time                `bson:"due"`
+	Note      *string                  `bson:"note"`
+	Items     []schemaItem             `bson:"items"`
+	Labels    map[string]string        `bson:"labels"`
+	Customer  mongoxentity.DBRef       `bson:"customer"`
+}
+
+// TestSchemaOfEncoded 按 mongoxcodec 编码后的 BSON 类型校验 schema
+func TestSchemaOfEncoded(t *testing.T) {
+	schema, err := SchemaOf[schemaSample]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	note := "n"
+	raw, _ := bson.Marshal(bson.M{"a": 1})
+	price, _ := primitive.ParseDecimal128("1.5")
+	for _, sample := range []schemaSample{{Raw: raw}, {
+		Id:        mongoxentity.NewStrObjectId(),
+		Amount:    decimal.NewFromFloat(1.5),
+		Count:     1 << 40,
+		Size:      7,
+		Ratio:     0.5,
+		Data:      []byte("x"),
+		Date:      primitive.NewDateTimeFromTime(time.Now()),
+		Price:     price,
+		Binary:    primitive.Binary{Data: []byte("x")},
+		Timestamp: primitive.Timestamp{T: 1},
+		Regex:     primitive.Regex{Pattern: "a"},
+		Doc:       bson.D{{Key: "a", Value: 1}},
+		Raw:       raw,
+		Due:       time.Now(),
+		Note:      &note,
+		Items:     []schemaItem{{Sku: "a", Qty: 1}},
+		Labels:    map[string]string{"a": "b"},
+		Customer:  mongoxentity.DBRef{Ref: "customers", ID: mongoxentity.NewStrObjectId()},
+	}} {
+		var buf bytes.Buffer
+		vw, err := bsonrw.NewBSONValueWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		enc, err := bson.NewEncoder(vw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.SetRegistry(mongoxcodec.NewRegistry()); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Encode(sample); err != nil {
+			t.Fatal(err)
+		}
+		checkSchema(t, "", schema, bson.RawValue{Type: bsontype.EmbeddedDocument, Value: buf.Bytes()})
+	}
+}
+
+var schemaTypeAliases = map[bsontype.Type]string{
+	bsontype.Double:           "double",
+	bsontype.String:           "string",
+	bsontype.EmbeddedDocument: "object",
+	bsontype.Array:            "array",
+	bsontype.Binary:           "binData",
+	bsontype.ObjectID:         "objectId",
+	bsontype.Boolean:          "bool",
+	bsontype.DateTime:         "date",
+	bsontype.Null:             "null",
+	bsontype.Regex:            "regex",
+	bsontype.Int32:            "int",
+	bsontype.Timestamp:        "timestamp",
+	bsontype.Int64:            "long",
+	bsontype.Decimal128:       "decimal",
+}
+
+// checkSchema 校验值的 BSON 类型、必填字段，并递归校验属性与数组元素
+func checkSchema(t *testing.T, path string, schema bson.D, value bson.RawValue) {
+	t.Helper()
+	m := schema.Map()
+	if types, ok := m["bsonType"]; ok {
+		allowed := bson.A{types}
+		if a, ok := types.(bson.A); ok {
+			allowed = a
+		}
+		matched := false
+		for _, v := range allowed {
+			matched = matched || v == schemaTypeAliases[value.Type]
+		}
+		if !matched {
+			t.Errorf("%s encoded as %s, schema bsonType = %v", path, value.Type, types)
+			return
+		}
+	}
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		doc := value.Document()
+		if required, ok := m["required"].(bson.A); ok {
+			for _, name := range required {
+				if _, err := doc.LookupErr(name.(string)); err != nil {
+					t.Errorf("%s missing required field %s", path, name)
+				}
+			}
+		}
+		if properties, ok := m["properties"].(bson.D); ok {
+			for _, p := range properties {
+				if v, err := doc.LookupErr(p.Key); err == nil {
+					checkSchema(t, path+"."+p.Key, p.Value.(bson.D), v)
+				}
+			}
+		}
+	case bsontype.Array:
+		if items, ok := m["items"].(bson.D); ok {
+			values, _ := value.Array().Values()
+			for _, v := range values {
+				checkSchema(t, path+"[]", items, v)
+			}
+		}
+	}
+}
+
+func TestSchemaOfUnsupported(t *testing.T) {
+	type invalid struct {
+		Ch  chan int  `bson:"ch"`
+		Due time.Time `bson:"due"`
+	}
+	if _, err := SchemaOf[invalid](); err == nil {
+		t.Error("SchemaOf() with chan field, want error")
+	}
+	type marshaler struct {
+		V customValue `bson:"v"`
+	}
+	if _, err := SchemaOf[marshaler](); err == nil {
+		t.Error("SchemaOf() with bson.ValueMarshaler field, want error")
+	}
+	if _, err := SchemaOf[int](); err == nil {
+		t.Error("SchemaOf[int]() want error")
+	}
+}
+
+type customValue struct{ N int }
+
+func (v customValue) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(v.N)
+}
@@ -0,0 +1,162 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aomi-go/data/common/auditor"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DeletedAtField 软删除时间字段
+	DeletedAtField = "deleted_at"
+	// DeletedByField 软删除操作人字段
+	DeletedByField = "deleted_by"
+)
+
+// ErrSoftDeleted 保存的实体已被软删除，需要先 Restore 再保存
+var ErrSoftDeleted = errors.New("mongo: document is soft deleted")
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted 返回的 context 在软删除模式下同样读取已删除的数据
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IsIncludeDeleted ctx 是否由 IncludeDeleted 创建
+func IsIncludeDeleted(ctx context.Context) bool {
+	if nil == ctx {
+		return false
+	}
+	v, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return v
+}
+
+func notDeletedFilter() bson.M {
+	return bson.M{DeletedAtField: nil}
+}
+
+// SetSoftDelete 开启软删除模式：DeleteById、Delete 只写入 deleted_at、deleted_by 标记，
+// 所有读取默认排除已删除的数据（见 IncludeDeleted），可通过 Restore、Purge 恢复或彻底删除。
+// Save、SaveMany 不会覆盖已删除的文档，返回 ErrSoftDeleted；使用 IncludeDeleted 的 context 时直接覆盖
+func (d *DocumentRepository[Entity]) SetSoftDelete(enabled bool) *DocumentRepository[Entity] {
+	d.softDelete = enabled
+	return d
+}
+
+// softDeleteUpdate 软删除的更新内容
+func (d *DocumentRepository[Entity]) softDeleteUpdate(ctx context.Context) bson.M {
+	set := bson.M{DeletedAtField: d.now()}
+	resolver := d.auditorResolver
+	if nil == resolver {
+		resolver = auditor.FromContext
+	}
+	if by, ok := resolver(ctx); ok {
+		set[DeletedByField] = by
+	}
+	return bson.M{"$set": set}
+}
+
+// saveFilter 按 _id 替换时的过滤条件，软删除模式下排除已删除的文档，避免保存时将其恢复
+func (d *DocumentRepository[Entity]) saveFilter(ctx context.Context, id primitive.ObjectID) bson.M {
+	filter := d.tenantIdFilter(ctx, id)
+	if d.softDelete && !IsIncludeDeleted(ctx) {
+		filter[DeletedAtField] = nil
+	}
+	return filter
+}
+
+// hasDeleted 保存冲突时判断 ids 中是否有已软删除的文档
+func (d *DocumentRepository[Entity]) hasDeleted(ctx context.Context, collection *mongo.Collection, ids []primitive.ObjectID) bool {
+	if !d.softDelete || IsIncludeDeleted(ctx) || len(ids) == 0 {
+		return false
+	}
+	filter := bson.M{"_id": bson.M{"$in": ids}, DeletedAtField: bson.M{"$ne": nil}}
+	if tenantId, scoped, _ := d.tenantOf(ctx); scoped {
+		filter[TenantIdField] = tenantId
+	}
+	n, err := collection.CountDocuments(ctx, filter)
+	return nil == err && n > 0
+}
+
+// softDeleteOptions 软删除以更新代替删除，沿用删除选项中的 collation、hint、comment、let
+func softDeleteOptions(opts []*options.DeleteOptions) *options.UpdateOptions {
+	result := options.Update()
+	for _, o := range opts {
+		if nil == o {
+			continue
+		}
+		if nil != o.Collation {
+			result.SetCollation(o.Collation)
+		}
+		if nil != o.Comment {
+			result.SetComment(o.Comment)
+		}
+		if nil != o.Hint {
+			result.SetHint(o.Hint)
+		}
+		if nil != o.Let {
+			result.SetLet(o.Let)
+		}
+	}
+	return result
+}
+
+// Restore 恢复满足条件的已软删除数据，返回恢复的数量
+func (d *DocumentRepository[Entity]) Restore(ctx context.Context, filter interface{}, opts ...*options.UpdateOptions) (restored int64, err error) {
+	ctx, done := d.observe(ctx, OpRestore)
+	defer func() { done(restored, err) }()
+
+	filter, err = d.tenantScope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	filter = andFilter(filter, bson.M{DeletedAtField: bson.M{"$ne": nil}})
+	update := bson.M{"$unset": bson.M{DeletedAtField: "", DeletedByField: ""}}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r, err := collection.UpdateMany(ctx, filter, update, opts...)
+	if nil != err {
+		return 0, err
+	}
+	return r.ModifiedCount, nil
+}
+
+// RestoreById 恢复已软删除的数据
+func (d *DocumentRepository[Entity]) RestoreById(ctx context.Context, id interface{}) (bool, error) {
+	n, err := d.Restore(ctx, bson.M{"_id": d.ToObjectId(id)})
+	return n > 0, err
+}
+
+// Purge 彻底删除满足条件的数据（包括已软删除的数据）
+func (d *DocumentRepository[Entity]) Purge(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (purged int64, err error) {
+	ctx, done := d.observe(ctx, OpPurge)
+	defer func() { done(purged, err) }()
+
+	filter, err = d.tenantScope(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r, err := collection.DeleteMany(ctx, filter, opts...)
+	if nil != err {
+		return 0, err
+	}
+	return r.DeletedCount, nil
+}
+
+// PurgeById 彻底删除数据
+func (d *DocumentRepository[Entity]) PurgeById(ctx context.Context, id interface{}) (bool, error) {
+	n, err := d.Purge(ctx, bson.M{"_id": d.ToObjectId(id)})
+	return n > 0, err
+}
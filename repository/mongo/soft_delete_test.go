@@ -0,0 +1,74 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aomi-go/data/common/auditor"
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSoftDeleteScope(t *testing.T) {
+	repo := (&DocumentRepository[User]{}).SetSoftDelete(true)
+	live := bson.M{"name": "a"}
+	deleted := bson.M{"name": "a", DeletedAtField: time.Now()}
+
+	for _, filter := range []interface{}{nil, bson.M{}, bson.M{"name": "a"}} {
+		scoped, _ := repo.scope(context.TODO(), filter)
+		if ok, err := matcher.Match(scoped, live); err != nil || !ok {
+			t.Errorf("scope(%v) excluded live document: %v", filter, err)
+		}
+		if ok, _ := matcher.Match(scoped, deleted); ok {
+			t.Errorf("scope(%v) included deleted document", filter)
+		}
+	}
+
+	scoped, _ := repo.scope(IncludeDeleted(context.TODO()), bson.M{"name": "a"})
+	if ok, _ := matcher.Match(scoped, deleted); !ok {
+		t.Errorf("scope() with IncludeDeleted excluded deleted document")
+	}
+
+	update := repo.softDeleteUpdate(auditor.WithAuditor(context.TODO(), "alice"))
+	set := update["$set"].(bson.M)
+	if set[DeletedByField] != "alice" || set[DeletedAtField] == nil {
+		t.Errorf("softDeleteUpdate() = %v", update)
+	}
+}
+
+func TestSoftDeleteSaveFilter(t *testing.T) {
+	repo := (&DocumentRepository[User]{}).SetSoftDelete(true)
+	id := primitive.NewObjectID()
+	live := bson.M{"_id": id}
+	deleted := bson.M{"_id": id, DeletedAtField: time.Now()}
+
+	// 保存时不匹配已删除的文档，upsert 因主键冲突失败而不是将其恢复
+	filter := repo.saveFilter(context.TODO(), id)
+	if ok, _ := matcher.Match(filter, live); !ok {
+		t.Errorf("saveFilter() = %v excluded live document", filter)
+	}
+	if ok, _ := matcher.Match(filter, deleted); ok {
+		t.Errorf("saveFilter() = %v matched deleted document", filter)
+	}
+	if filter := repo.saveFilter(IncludeDeleted(context.TODO()), id); len(filter) != 1 {
+		t.Errorf("saveFilter() with IncludeDeleted = %v", filter)
+	}
+	if filter := (&DocumentRepository[User]{}).saveFilter(context.TODO(), id); len(filter) != 1 {
+		t.Errorf("saveFilter() without soft delete = %v", filter)
+	}
+}
+
+func TestSoftDeleteOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "zh"}
+	opts := softDeleteOptions([]*options.DeleteOptions{
+		options.Delete().SetCollation(collation),
+		nil,
+		options.Delete().SetHint("name_1").SetComment("purge"),
+	})
+	if opts.Collation != collation || opts.Hint != "name_1" || opts.Comment != "purge" || nil != opts.Let {
+		t.Errorf("softDeleteOptions() = %+v", opts)
+	}
+}
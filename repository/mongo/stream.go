@@ -0,0 +1,158 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrStopIteration 回调返回该错误时提前结束遍历，ForEach、ForEachBatch 返回 nil
+var ErrStopIteration = errors.New("stop iteration")
+
+// Iterator 逐条解码查询结果，使用完毕后需要调用 Close
+//
+//	it, err := repo.Iterate(ctx, filter, options.Find().SetBatchSize(500))
+//	defer it.Close()
+//	for it.Next() {
+//		entity := it.Entity()
+//	}
+//	err = it.Err()
+type Iterator[Entity interface{}] struct {
+	ctx     context.Context
+	cursor  *mongo.Cursor
+	current *Entity
+	err     error
+}
+
+func newIterator[Entity interface{}](ctx context.Context, cursor *mongo.Cursor) *Iterator[Entity] {
+	return &Iterator[Entity]{ctx: ctx, cursor: cursor}
+}
+
+// Next 解码下一条数据，没有更多数据、出错或 context 被取消时返回 false
+func (it *Iterator[Entity]) Next() bool {
+	it.current = nil
+	if nil != it.err {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	if !it.cursor.Next(it.ctx) {
+		it.err = it.cursor.Err()
+		return false
+	}
+	var entity Entity
+	if err := it.cursor.Decode(&entity); err != nil {
+		it.err = err
+		return false
+	}
+	if err := afterLoad(it.ctx, &entity); err != nil {
+		it.err = err
+		return false
+	}
+	it.current = &entity
+	return true
+}
+
+// Entity 当前数据
+func (it *Iterator[Entity]) Entity() *Entity {
+	return it.current
+}
+
+// Err 遍历过程中的错误
+func (it *Iterator[Entity]) Err() error {
+	return it.err
+}
+
+// Close 关闭游标
+func (it *Iterator[Entity]) Close() error {
+	return it.cursor.Close(context.WithoutCancel(it.ctx))
+}
+
+// Iterate 返回逐条解码的迭代器，批大小通过 options.Find().SetBatchSize 设置
+func (d *DocumentRepository[Entity]) Iterate(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*Iterator[Entity], error) {
+	cursor, err := d.FindWithCursor(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator[Entity](ctx, cursor), nil
+}
+
+// ForEach 逐条处理查询结果，fn 返回 ErrStopIteration 时提前结束，返回其他错误时结束并返回该错误
+func (d *DocumentRepository[Entity]) ForEach(ctx context.Context, filter interface{}, fn func(entity *Entity) error, opts ...*options.FindOptions) error {
+	it, err := d.Iterate(ctx, filter, opts...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	return forEach(it, fn)
+}
+
+// ForEachBatch 按 batchSize 分批处理查询结果，最后一批可能不足 batchSize；
+// 未在 opts 中设置批大小时使用 batchSize 作为游标的批大小
+func (d *DocumentRepository[Entity]) ForEachBatch(ctx context.Context, filter interface{}, batchSize int, fn func(entities []*Entity) error, opts ...*options.FindOptions) error {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	batched := opts
+	if !hasBatchSize(opts) {
+		batched = append(append([]*options.FindOptions{}, opts...), options.Find().SetBatchSize(int32(batchSize)))
+	}
+	it, err := d.Iterate(ctx, filter, batched...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	return forEachBatch(it, batchSize, fn)
+}
+
+func hasBatchSize(opts []*options.FindOptions) bool {
+	for _, opt := range opts {
+		if nil != opt && nil != opt.BatchSize {
+			return true
+		}
+	}
+	return false
+}
+
+func forEach[Entity interface{}](it *Iterator[Entity], fn func(entity *Entity) error) error {
+	for it.Next() {
+		if err := fn(it.Entity()); err != nil {
+			return stopped(err)
+		}
+	}
+	return it.Err()
+}
+
+func forEachBatch[Entity interface{}](it *Iterator[Entity], batchSize int, fn func(entities []*Entity) error) error {
+	batch := make([]*Entity, 0, batchSize)
+	for it.Next() {
+		batch = append(batch, it.Entity())
+		if len(batch) < batchSize {
+			continue
+		}
+		if err := fn(batch); err != nil {
+			return stopped(err)
+		}
+		// 交给回调的切片可能被保留，不复用底层数组
+		batch = make([]*Entity, 0, batchSize)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return stopped(fn(batch))
+	}
+	return nil
+}
+
+// stopped 将 ErrStopIteration 视为正常结束
+func stopped(err error) error {
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+	return err
+}
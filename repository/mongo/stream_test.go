@@ -0,0 +1,83 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newTestIterator(t *testing.T, ctx context.Context, n int) *Iterator[User] {
+	docs := make([]interface{}, n)
+	for i := range docs {
+		docs[i] = bson.M{"name": string(rune('a' + i))}
+	}
+	cursor, err := mongo.NewCursorFromDocuments(docs, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newIterator[User](ctx, cursor)
+}
+
+func TestIterator(t *testing.T) {
+	it := newTestIterator(t, context.TODO(), 3)
+	var names string
+	for it.Next() {
+		names += it.Entity().Name
+	}
+	if err := it.Err(); err != nil || names != "abc" {
+		t.Fatalf("Iterator = %q, %v", names, err)
+	}
+	if err := it.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	it = newTestIterator(t, ctx, 3)
+	it.Next()
+	cancel()
+	if it.Next() || !errors.Is(it.Err(), context.Canceled) {
+		t.Fatalf("Next() after cancel, Err() = %v", it.Err())
+	}
+}
+
+func TestForEach(t *testing.T) {
+	var names string
+	err := forEach(newTestIterator(t, context.TODO(), 5), func(u *User) error {
+		names += u.Name
+		if u.Name == "c" {
+			return ErrStopIteration
+		}
+		return nil
+	})
+	if err != nil || names != "abc" {
+		t.Fatalf("forEach() = %q, %v", names, err)
+	}
+
+	boom := errors.New("boom")
+	if err := forEach(newTestIterator(t, context.TODO(), 5), func(*User) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("forEach() error = %v, want boom", err)
+	}
+}
+
+func TestForEachBatch(t *testing.T) {
+	var sizes []int
+	err := forEachBatch(newTestIterator(t, context.TODO(), 5), 2, func(users []*User) error {
+		sizes = append(sizes, len(users))
+		return nil
+	})
+	if err != nil || len(sizes) != 3 || sizes[0] != 2 || sizes[2] != 1 {
+		t.Fatalf("forEachBatch() = %v, %v", sizes, err)
+	}
+
+	sizes = nil
+	err = forEachBatch(newTestIterator(t, context.TODO(), 5), 2, func(users []*User) error {
+		sizes = append(sizes, len(users))
+		return ErrStopIteration
+	})
+	if err != nil || len(sizes) != 1 {
+		t.Fatalf("forEachBatch() with stop = %v, %v", sizes, err)
+	}
+}
@@ -0,0 +1,218 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/tenant"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TenantIdField 租户 ID 字段
+const TenantIdField = "tenant_id"
+
+// SetMultiTenant 开启多租户模式：所有读取、更新、删除都限定为 context 中的租户（见 tenant.WithTenant），
+// Save、SaveMany 写入租户 ID（实体需要实现 mongoxentity.TenantAware），更新操作不能修改租户 ID。
+// context 中没有租户时返回 common.ErrTenantRequired，访问其他租户的数据时返回 common.ErrCrossTenant，
+// 系统 context（见 tenant.WithSystem）不受限制
+func (d *DocumentRepository[Entity]) SetMultiTenant(enabled bool) *DocumentRepository[Entity] {
+	d.multiTenant = enabled
+	return d
+}
+
+// tenantOf 返回需要限定的租户，未开启多租户模式或为系统 context 时 scoped 为 false
+func (d *DocumentRepository[Entity]) tenantOf(ctx context.Context) (tenantId string, scoped bool, err error) {
+	if !d.multiTenant || tenant.IsSystem(ctx) {
+		return "", false, nil
+	}
+	tenantId, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", false, common.ErrTenantRequired
+	}
+	return tenantId, true, nil
+}
+
+// tenantScope 为过滤条件追加租户条件，过滤条件中显式指定了其他租户时返回 common.ErrCrossTenant
+func (d *DocumentRepository[Entity]) tenantScope(ctx context.Context, filter interface{}) (interface{}, error) {
+	tenantId, scoped, err := d.tenantOf(ctx)
+	if err != nil || !scoped {
+		return filter, err
+	}
+	if v, ok := topLevelValue(filter, TenantIdField); ok {
+		if s, ok := v.(string); !ok || s != tenantId {
+			return nil, common.ErrCrossTenant
+		}
+	}
+	return andFilter(filter, bson.M{TenantIdField: tenantId}), nil
+}
+
+// stampTenant 为实体写入 context 中的租户，实体已属于其他租户时返回 common.ErrCrossTenant
+func (d *DocumentRepository[Entity]) stampTenant(ctx context.Context, entity *Entity) error {
+	tenantId, scoped, err := d.tenantOf(ctx)
+	if err != nil || !scoped {
+		return err
+	}
+	var e interface{} = entity
+	t, ok := e.(mongoxentity.TenantAware)
+	if !ok {
+		return fmt.Errorf("mongo: %T does not implement mongoxentity.TenantAware", entity)
+	}
+	switch t.GetTenantId() {
+	case "":
+		t.SetTenantId(tenantId)
+	case tenantId:
+	default:
+		return common.ErrCrossTenant
+	}
+	return nil
+}
+
+// tenantIdFilter 按 _id 写入时的过滤条件，多租户模式下追加租户条件，避免覆盖其他租户的同 ID 文档
+func (d *DocumentRepository[Entity]) tenantIdFilter(ctx context.Context, id primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": id}
+	if tenantId, scoped, _ := d.tenantOf(ctx); scoped {
+		filter[TenantIdField] = tenantId
+	}
+	return filter
+}
+
+// tenantUpdateGuard 多租户模式下拒绝修改租户字段的更新（$set 为当前租户除外），避免将文档移动到其他租户。
+// 更新管道中的 $project、$replaceRoot、$replaceWith 无法判断是否保留租户字段，一并拒绝
+func (d *DocumentRepository[Entity]) tenantUpdateGuard(ctx context.Context, update interface{}) error {
+	tenantId, scoped, err := d.tenantOf(ctx)
+	if err != nil || !scoped {
+		return err
+	}
+	stages := bson.A{update}
+	pipeline := false
+	if _, ok := update.(bson.D); !ok {
+		if v := reflect.ValueOf(update); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			if stages, err = pipelineOf(update); err != nil {
+				return err
+			}
+			pipeline = true
+		}
+	}
+	for _, stage := range stages {
+		doc, err := toDocument(stage)
+		if err != nil {
+			return err
+		}
+		for _, op := range doc {
+			if err := checkTenantOperator(op, tenantId, pipeline); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkTenantOperator 检查单个更新操作符是否修改租户字段
+func checkTenantOperator(op bson.E, tenantId string, pipeline bool) error {
+	switch op.Key {
+	case "$set", "$setOnInsert", "$addFields":
+		fields, err := toDocument(op.Value)
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if isTenantField(f.Key) {
+				if s, ok := f.Value.(string); !ok || s != tenantId || f.Key != TenantIdField {
+					return common.ErrCrossTenant
+				}
+			}
+		}
+		return nil
+	case "$project", "$replaceRoot", "$replaceWith":
+		if pipeline {
+			return common.ErrCrossTenant
+		}
+	case "$unset":
+		if pipeline {
+			// 管道中的 $unset 为字段名或字段名数组
+			names := bson.A{op.Value}
+			if a, ok := op.Value.(bson.A); ok {
+				names = a
+			}
+			for _, name := range names {
+				if s, ok := name.(string); ok && isTenantField(s) {
+					return common.ErrCrossTenant
+				}
+			}
+			return nil
+		}
+	}
+	if !strings.HasPrefix(op.Key, "$") {
+		// 替换文档
+		if isTenantField(op.Key) {
+			if s, ok := op.Value.(string); !ok || s != tenantId || op.Key != TenantIdField {
+				return common.ErrCrossTenant
+			}
+		}
+		return nil
+	}
+	fields, err := toDocument(op.Value)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if isTenantField(f.Key) {
+			return common.ErrCrossTenant
+		}
+		// $rename 的目标字段
+		if s, ok := f.Value.(string); ok && op.Key == "$rename" && isTenantField(s) {
+			return common.ErrCrossTenant
+		}
+	}
+	return nil
+}
+
+func isTenantField(key string) bool {
+	return key == TenantIdField || strings.HasPrefix(key, TenantIdField+".")
+}
+
+// toDocument 将文档转换为 bson.D
+func toDocument(v interface{}) (bson.D, error) {
+	if doc, ok := v.(bson.D); ok {
+		return doc, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: invalid document %T: %w", v, err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// isIdDuplicateKeyError 是否为 _id 主键冲突，多租户模式下表示同 ID 的文档属于其他租户
+func isIdDuplicateKeyError(err error) bool {
+	return mongo.IsDuplicateKeyError(err) && strings.Contains(err.Error(), "_id_")
+}
+
+// topLevelValue 读取过滤条件顶层字段的值
+func topLevelValue(filter interface{}, key string) (interface{}, bool) {
+	switch f := filter.(type) {
+	case bson.M:
+		v, ok := f[key]
+		return v, ok
+	case map[string]interface{}:
+		v, ok := f[key]
+		return v, ok
+	case bson.D:
+		for _, e := range f {
+			if e.Key == key {
+				return e.Value, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,126 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/tenant"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"github.com/aomi-go/data/repository/mongo/matcher"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type tenantUser struct {
+	mongoxentity.TenantFields `bson:",inline"`
+	ID                        mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Name                      string                   `bson:"name"`
+}
+
+func TestTenantScope(t *testing.T) {
+	repo := (&DocumentRepository[tenantUser]{}).SetMultiTenant(true).SetSoftDelete(true)
+	ctx := tenant.WithTenant(context.TODO(), "t1")
+	own := bson.M{"name": "a", TenantIdField: "t1"}
+	other := bson.M{"name": "a", TenantIdField: "t2"}
+
+	for _, filter := range []interface{}{nil, bson.M{"name": "a"}, bson.M{TenantIdField: "t1"}} {
+		scoped, err := repo.scope(ctx, filter)
+		if err != nil {
+			t.Fatalf("scope(%v) error = %v", filter, err)
+		}
+		if ok, err := matcher.Match(scoped, own); err != nil || !ok {
+			t.Errorf("scope(%v) excluded own document: %v", filter, err)
+		}
+		if ok, _ := matcher.Match(scoped, other); ok {
+			t.Errorf("scope(%v) included other tenant's document", filter)
+		}
+	}
+
+	if _, err := repo.scope(ctx, bson.M{TenantIdField: "t2"}); !errors.Is(err, common.ErrCrossTenant) {
+		t.Errorf("scope() with other tenant error = %v, want ErrCrossTenant", err)
+	}
+	if _, err := repo.scope(ctx, bson.D{{Key: TenantIdField, Value: bson.M{"$ne": "t1"}}}); !errors.Is(err, common.ErrCrossTenant) {
+		t.Errorf("scope() with tenant operator error = %v, want ErrCrossTenant", err)
+	}
+	if _, err := repo.scope(context.TODO(), bson.M{}); !errors.Is(err, common.ErrTenantRequired) {
+		t.Errorf("scope() without tenant error = %v, want ErrTenantRequired", err)
+	}
+
+	scoped, err := repo.scope(tenant.WithSystem(context.TODO()), bson.M{"name": "a"})
+	if err != nil {
+		t.Fatalf("scope() in system context error = %v", err)
+	}
+	if ok, _ := matcher.Match(scoped, other); !ok {
+		t.Errorf("scope() in system context excluded other tenant's document")
+	}
+}
+
+func TestStampTenant(t *testing.T) {
+	repo := (&DocumentRepository[tenantUser]{}).SetMultiTenant(true)
+	ctx := tenant.WithTenant(context.TODO(), "t1")
+
+	u := &tenantUser{}
+	if err := repo.stampTenant(ctx, u); err != nil || u.TenantId != "t1" {
+		t.Fatalf("stampTenant() = %v, tenant %q", err, u.TenantId)
+	}
+	if err := repo.stampTenant(ctx, &tenantUser{TenantFields: mongoxentity.TenantFields{TenantId: "t2"}}); !errors.Is(err, common.ErrCrossTenant) {
+		t.Errorf("stampTenant() other tenant error = %v, want ErrCrossTenant", err)
+	}
+	if err := repo.stampTenant(context.TODO(), &tenantUser{}); !errors.Is(err, common.ErrTenantRequired) {
+		t.Errorf("stampTenant() without tenant error = %v, want ErrTenantRequired", err)
+	}
+	if err := repo.stampTenant(tenant.WithSystem(context.TODO()), &tenantUser{}); err != nil {
+		t.Errorf("stampTenant() in system context error = %v", err)
+	}
+	if err := (&DocumentRepository[User]{}).SetMultiTenant(true).stampTenant(ctx, &User{}); err == nil {
+		t.Errorf("stampTenant() accepted entity without tenant field")
+	}
+
+	filter := repo.tenantIdFilter(ctx, u.ID.ObjectId())
+	if filter[TenantIdField] != "t1" {
+		t.Errorf("tenantIdFilter() = %v", filter)
+	}
+}
+
+func TestTenantUpdateGuard(t *testing.T) {
+	repo := (&DocumentRepository[tenantUser]{}).SetMultiTenant(true)
+	ctx := tenant.WithTenant(context.TODO(), "t1")
+
+	for _, update := range []interface{}{
+		bson.M{"$set": bson.M{"name": "b"}},
+		bson.M{"$set": bson.M{TenantIdField: "t1"}},
+		bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: TenantIdField, Value: "t1"}}}},
+		bson.M{"$rename": bson.M{"name": "title"}},
+		bson.A{bson.M{"$set": bson.M{"name": "b"}}, bson.M{"$unset": "title"}},
+	} {
+		if err := repo.tenantUpdateGuard(ctx, update); err != nil {
+			t.Errorf("tenantUpdateGuard(%v) error = %v", update, err)
+		}
+	}
+	for _, update := range []interface{}{
+		bson.M{"$set": bson.M{TenantIdField: "t2"}},
+		bson.M{"$set": bson.M{TenantIdField + ".x": "t1"}},
+		bson.M{"$setOnInsert": bson.M{TenantIdField: "t2"}},
+		bson.M{"$unset": bson.M{TenantIdField: ""}},
+		bson.M{"$rename": bson.M{TenantIdField: "old"}},
+		bson.M{"$rename": bson.M{"name": TenantIdField}},
+		bson.A{bson.M{"$set": bson.M{TenantIdField: "$name"}}},
+		bson.A{bson.M{"$unset": bson.A{"name", TenantIdField}}},
+		bson.A{bson.M{"$replaceWith": bson.M{"name": "b"}}},
+	} {
+		if err := repo.tenantUpdateGuard(ctx, update); !errors.Is(err, common.ErrCrossTenant) {
+			t.Errorf("tenantUpdateGuard(%v) error = %v, want ErrCrossTenant", update, err)
+		}
+	}
+
+	if _, err := repo.UpdateMany(ctx, bson.M{}, bson.M{"$set": bson.M{TenantIdField: "t2"}}); !errors.Is(err, common.ErrCrossTenant) {
+		t.Errorf("UpdateMany() error = %v, want ErrCrossTenant", err)
+	}
+	if _, err := repo.FindOneAndModify(ctx, bson.M{}, bson.M{"$unset": bson.M{TenantIdField: ""}}); !errors.Is(err, common.ErrCrossTenant) {
+		t.Errorf("FindOneAndModify() error = %v, want ErrCrossTenant", err)
+	}
+	if err := repo.tenantUpdateGuard(tenant.WithSystem(context.TODO()), bson.M{"$set": bson.M{TenantIdField: "t2"}}); err != nil {
+		t.Errorf("tenantUpdateGuard() in system context error = %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// versionField 实体的乐观锁版本字段
+type versionField struct {
+	// key 版本字段在文档中的名称
+	key string
+	// index 带 mongox:"version" 标签的字段下标，为空时通过 mongoxentity.Versioned 读写
+	index []int
+}
+
+var versionFields sync.Map
+
+// versionFieldOf 查找实体的版本字段：优先使用 mongox:"version" 标签，其次为 mongoxentity.Versioned 接口（字段名 version）
+func versionFieldOf(entity interface{}) (*versionField, bool) {
+	t := reflect.TypeOf(entity)
+	if v, ok := versionFields.Load(t); ok {
+		f := v.(*versionField)
+		return f, nil != f
+	}
+
+	var f *versionField
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		f = findVersionTag(t.Elem(), nil)
+	}
+	if nil == f {
+		if _, ok := entity.(mongoxentity.Versioned); ok {
+			f = &versionField{key: "version"}
+		}
+	}
+	versionFields.Store(t, f)
+	return f, nil != f
+}
+
+func findVersionTag(t reflect.Type, parent []int) *versionField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tags, err := bsoncodec.DefaultStructTagParser.ParseStructTags(sf)
+		if err != nil || tags.Skip {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		if tags.Inline && sf.Type.Kind() == reflect.Struct {
+			if f := findVersionTag(sf.Type, index); nil != f {
+				return f
+			}
+			continue
+		}
+		if !hasTagOption(sf.Tag.Get("mongox"), "version") {
+			continue
+		}
+		switch sf.Type.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+			return &versionField{key: tags.Name, index: index}
+		}
+	}
+	return nil
+}
+
+// hasTagOption 判断逗号分隔的标签中是否包含 option
+func hasTagOption(tag string, option string) bool {
+	for _, part := range strings.Split(tag, ",") {
+		if strings.TrimSpace(part) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *versionField) get(entity interface{}) int64 {
+	if nil == f.index {
+		return entity.(mongoxentity.Versioned).GetVersion()
+	}
+	return reflect.ValueOf(entity).Elem().FieldByIndex(f.index).Int()
+}
+
+func (f *versionField) set(entity interface{}, version int64) {
+	if nil == f.index {
+		entity.(mongoxentity.Versioned).SetVersion(version)
+		return
+	}
+	reflect.ValueOf(entity).Elem().FieldByIndex(f.index).SetInt(version)
+}
+
+// filter 期望版本的过滤条件，版本为 0 时同样匹配尚未写入版本号的历史文档
+func (f *versionField) filter(version int64) bson.M {
+	if version == 0 {
+		return bson.M{f.key: bson.M{"$in": bson.A{nil, 0}}}
+	}
+	return bson.M{f.key: version}
+}
+
+// guard 在 filter 中加入期望版本条件并递增实体的版本号，返回期望版本
+func (f *versionField) guard(entity interface{}, filter bson.M) int64 {
+	expected := f.get(entity)
+	for k, v := range f.filter(expected) {
+		filter[k] = v
+	}
+	f.set(entity, expected+1)
+	return expected
+}
+
+// versionConflict 带版本条件的替换是否冲突：err 为 _id 主键冲突（版本为 0 时 upsert，但已存在版本不同的文档），
+// 或匹配与插入的文档数少于替换的数量。其他唯一索引冲突不是并发修改，不视为冲突
+func versionConflict(err error, replaces int64, matched int64, upserted int64) bool {
+	if err != nil {
+		return isIdDuplicateKeyError(err)
+	}
+	return matched+upserted < replaces
+}
+
+// saveWithVersion 按 _id 与期望版本条件替换文档并递增版本号，文档已被并发修改时返回 common.ErrOptimisticLock
+func (d *DocumentRepository[Entity]) saveWithVersion(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, entity *Entity, f *versionField) (*Entity, error) {
+	filter := d.saveFilter(ctx, id)
+	expected := f.guard(entity, filter)
+
+	// 版本为 0 时允许插入新文档，若文档已存在且版本不同会触发主键冲突
+	opts := options.Replace().SetUpsert(expected == 0)
+	r, err := collection.ReplaceOne(ctx, filter, entity, opts)
+	var matched, upserted int64
+	if nil != r {
+		matched, upserted = r.MatchedCount, r.UpsertedCount
+	}
+	if versionConflict(err, 1, matched, upserted) {
+		f.set(entity, expected)
+		if d.hasDeleted(ctx, collection, []primitive.ObjectID{id}) {
+			return nil, ErrSoftDeleted
+		}
+		return nil, common.ErrOptimisticLock
+	}
+	if err != nil {
+		f.set(entity, expected)
+		return nil, err
+	}
+	return entity, nil
+}
@@ -0,0 +1,139 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aomi-go/data/mongo/mongoxentity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type versionedByEmbed struct {
+	mongoxentity.Versioning `bson:",inline"`
+	Name                    string `bson:"name"`
+}
+
+type versionedByTag struct {
+	Rev int32 `bson:"rev" mongox:"version"`
+}
+
+type customVersioned struct {
+	v int64
+}
+
+func (c *customVersioned) GetVersion() int64        { return c.v }
+func (c *customVersioned) SetVersion(version int64) { c.v = version }
+
+func TestVersionFieldOf(t *testing.T) {
+	embed := &versionedByEmbed{}
+	f, ok := versionFieldOf(embed)
+	if !ok || f.key != "version" {
+		t.Fatalf("versionFieldOf(embed) = %+v, %v", f, ok)
+	}
+	f.set(embed, 3)
+	if embed.Version != 3 || f.get(embed) != 3 {
+		t.Errorf("set/get = %d", embed.Version)
+	}
+
+	tagged := &versionedByTag{}
+	f, ok = versionFieldOf(tagged)
+	if !ok || f.key != "rev" {
+		t.Fatalf("versionFieldOf(tagged) = %+v, %v", f, ok)
+	}
+	f.set(tagged, 2)
+	if tagged.Rev != 2 {
+		t.Errorf("set() rev = %d", tagged.Rev)
+	}
+
+	custom := &customVersioned{}
+	if f, ok = versionFieldOf(custom); !ok || f.key != "version" {
+		t.Fatalf("versionFieldOf(custom) = %+v, %v", f, ok)
+	}
+	f.set(custom, 7)
+	if custom.v != 7 {
+		t.Errorf("set() via interface = %d", custom.v)
+	}
+
+	if _, ok := versionFieldOf(&User{}); ok {
+		t.Errorf("versionFieldOf(User) = true, want false")
+	}
+}
+
+func TestVersionGuard(t *testing.T) {
+	entity := &versionedByTag{}
+	f, _ := versionFieldOf(entity)
+
+	filter := bson.M{"_id": 1}
+	if expected := f.guard(entity, filter); expected != 0 || entity.Rev != 1 {
+		t.Fatalf("guard() = %d, rev = %d", expected, entity.Rev)
+	}
+	// 版本为 0 时匹配没有版本号的历史文档
+	if want := (bson.M{"_id": 1, "rev": bson.M{"$in": bson.A{nil, 0}}}); !reflect.DeepEqual(filter, want) {
+		t.Errorf("guard() filter = %v, want %v", filter, want)
+	}
+
+	filter = bson.M{"_id": 1}
+	if expected := f.guard(entity, filter); expected != 1 || entity.Rev != 2 {
+		t.Fatalf("guard() = %d, rev = %d", expected, entity.Rev)
+	}
+	if want := (bson.M{"_id": 1, "rev": int64(1)}); !reflect.DeepEqual(filter, want) {
+		t.Errorf("guard() filter = %v, want %v", filter, want)
+	}
+}
+
+func TestVersionConflict(t *testing.T) {
+	duplicate := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000,
+		Message: "E11000 duplicate key error collection: app.user index: _id_ dup key: { _id: 1 }"}}}
+	unique := mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000,
+		Message: "E11000 duplicate key error collection: app.user index: email_1 dup key: { email: \"a\" }"}}}
+	tests := []struct {
+		name                        string
+		err                         error
+		replaces, matched, upserted int64
+		want                        bool
+	}{
+		{"matched", nil, 1, 1, 0, false},
+		{"upserted", nil, 1, 0, 1, false},
+		{"version changed", nil, 1, 0, 0, true},
+		{"duplicate key on upsert", duplicate, 1, 0, 0, true},
+		// 其他唯一索引冲突原样返回
+		{"unique index", unique, 1, 0, 0, false},
+		{"other error", errors.New("boom"), 1, 0, 0, false},
+		// SaveMany 中部分替换未匹配
+		{"partial", nil, 3, 1, 1, true},
+		{"all matched", nil, 3, 2, 1, false},
+	}
+	for _, tt := range tests {
+		if got := versionConflict(tt.err, tt.replaces, tt.matched, tt.upserted); got != tt.want {
+			t.Errorf("%s: versionConflict() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+type versionedDoc struct {
+	ID  mongoxentity.StrObjectId `bson:"_id,omitempty"`
+	Rev int64                    `bson:"rev" mongox:"version"`
+}
+
+func TestSaveManyRestoresOnError(t *testing.T) {
+	failure := errors.New("boom")
+	repo := (&DocumentRepository[versionedDoc]{}).SetCollectionResolver(CollectionResolverFunc(
+		func(ctx context.Context, db *mongo.Database, collectionName string) (*mongo.Database, string, error) {
+			return nil, "", failure
+		}))
+	id := mongoxentity.NewStrObjectId()
+	existing := &versionedDoc{ID: id, Rev: 2}
+	created := &versionedDoc{}
+	if _, err := repo.SaveMany(context.TODO(), []*versionedDoc{existing, created}); !errors.Is(err, failure) {
+		t.Fatalf("SaveMany() error = %v, want %v", err, failure)
+	}
+	if existing.ID != id || existing.Rev != 2 {
+		t.Errorf("existing entity = %+v, want unchanged", existing)
+	}
+	if created.ID != "" || created.Rev != 0 {
+		t.Errorf("new entity = %+v, want unchanged", created)
+	}
+}
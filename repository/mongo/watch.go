@@ -0,0 +1,356 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	OperationInsert     = "insert"
+	OperationUpdate     = "update"
+	OperationReplace    = "replace"
+	OperationDelete     = "delete"
+	OperationInvalidate = "invalidate"
+)
+
+// ChangeEvent 类型化的变更事件
+type ChangeEvent[Entity interface{}] struct {
+	ResumeToken   bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	// FullDocument 删除事件为空，更新事件为查询时的最新文档（文档已被删除时为空）
+	FullDocument *Entity `bson:"fullDocument"`
+	// FullDocumentBeforeChange 变更前的文档，需要 SetFullDocumentBeforeChange 且集合开启 changeStreamPreAndPostImages
+	FullDocumentBeforeChange *Entity             `bson:"fullDocumentBeforeChange,omitempty"`
+	DocumentKey              bson.M              `bson:"documentKey"`
+	UpdateDescription        *UpdateDescription  `bson:"updateDescription,omitempty"`
+	ClusterTime              primitive.Timestamp `bson:"clusterTime"`
+}
+
+// ErrPreImageRequired 多租户模式下删除事件只能通过变更前的文档按租户过滤
+var ErrPreImageRequired = errors.New("mongo: watching a multi-tenant collection requires SetFullDocumentBeforeChange")
+
+// ID 文档的 _id
+func (e *ChangeEvent[Entity]) ID() interface{} {
+	return e.DocumentKey["_id"]
+}
+
+// UpdateDescription 更新事件修改与删除的字段
+type UpdateDescription struct {
+	UpdatedFields bson.M   `bson:"updatedFields"`
+	RemovedFields []string `bson:"removedFields"`
+}
+
+// ResumeTokenStore 持久化变更流的 resume token，key 为订阅者名称
+type ResumeTokenStore interface {
+	// Load 读取 token，不存在时返回 nil
+	Load(ctx context.Context, key string) (bson.Raw, error)
+	Save(ctx context.Context, key string, token bson.Raw) error
+}
+
+// WatchOptions 订阅选项
+type WatchOptions struct {
+	filter         interface{}
+	operationTypes []string
+	store          ResumeTokenStore
+	key            string
+	fullDocument   options.FullDocument
+	// beforeChange 为空时不返回变更前的文档
+	beforeChange options.FullDocument
+	batchSize    *int32
+}
+
+func NewWatchOptions() *WatchOptions {
+	return &WatchOptions{fullDocument: options.UpdateLookup}
+}
+
+// SetFilter 按文档内容过滤，filter 可以为 *QueryBuilder，字段与实体一致。
+// 删除事件没有文档内容，设置 SetFullDocumentBeforeChange 时按变更前的文档过滤，否则不受过滤条件限制
+func (o *WatchOptions) SetFilter(filter interface{}) *WatchOptions {
+	o.filter = filter
+	return o
+}
+
+// SetOperationTypes 只订阅指定的操作类型，如 OperationInsert、OperationUpdate
+func (o *WatchOptions) SetOperationTypes(types ...string) *WatchOptions {
+	o.operationTypes = types
+	return o
+}
+
+// SetResumeTokenStore 处理完成的事件的 token 保存在 store 中，重新订阅时从 key 对应的 token 之后继续
+func (o *WatchOptions) SetResumeTokenStore(store ResumeTokenStore, key string) *WatchOptions {
+	o.store = store
+	o.key = key
+	return o
+}
+
+// SetFullDocument 更新事件的 fullDocument 模式，默认为 options.UpdateLookup
+func (o *WatchOptions) SetFullDocument(fullDocument options.FullDocument) *WatchOptions {
+	o.fullDocument = fullDocument
+	return o
+}
+
+// SetFullDocumentBeforeChange 返回变更前的文档（options.Required 或 options.WhenAvailable），删除事件按变更前的文档过滤，
+// 集合需开启 changeStreamPreAndPostImages。WhenAvailable 时没有变更前文档的删除事件会被过滤掉
+func (o *WatchOptions) SetFullDocumentBeforeChange(mode options.FullDocument) *WatchOptions {
+	o.beforeChange = mode
+	return o
+}
+
+func (o *WatchOptions) SetBatchSize(size int32) *WatchOptions {
+	o.batchSize = &size
+	return o
+}
+
+// changeStream mongo.ChangeStream 中用到的方法
+type changeStream interface {
+	Next(ctx context.Context) bool
+	Decode(v interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// Watch 订阅集合的变更并逐个交给 handler 处理，阻塞直到 ctx 结束或出错。
+// handler 返回 nil 后才保存 resume token，重启后从未处理的事件继续，事件至少被处理一次；
+// invalidate 事件之后变更流结束，重新订阅时从 invalidate 之后开启新的变更流（需要 MongoDB 4.2 及以上）；
+// handler 返回 ErrStopIteration 时停止订阅并返回 nil。
+// 多租户模式下按 ctx 中的租户过滤，需要设置 SetFullDocumentBeforeChange 使删除事件同样按租户过滤，否则返回 ErrPreImageRequired；
+// 软删除产生的更新事件不会被过滤
+func (d *DocumentRepository[Entity]) Watch(ctx context.Context, opts *WatchOptions, handler func(ctx context.Context, event *ChangeEvent[Entity]) error) error {
+	if nil == opts {
+		opts = NewWatchOptions()
+	}
+	stream, err := d.watch(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+	return consumeChanges(ctx, stream, opts, handler)
+}
+
+// WatchChan 与 Watch 相同，事件通过 channel 投递，事件被接收后保存 resume token。
+// 订阅结束时关闭两个 channel，出错时先向 errs 发送错误
+func (d *DocumentRepository[Entity]) WatchChan(ctx context.Context, opts *WatchOptions) (<-chan *ChangeEvent[Entity], <-chan error) {
+	events := make(chan *ChangeEvent[Entity])
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(events)
+		err := d.Watch(ctx, opts, func(ctx context.Context, event *ChangeEvent[Entity]) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if nil != err {
+			errs <- err
+		}
+	}()
+	return events, errs
+}
+
+func (d *DocumentRepository[Entity]) watch(ctx context.Context, opts *WatchOptions) (changeStream, error) {
+	filter := opts.filter
+	if qb, ok := filter.(*QueryBuilder); ok {
+		filter = qb.Build()
+	}
+	_, scoped, err := d.tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	preImages := "" != opts.beforeChange && options.Off != opts.beforeChange
+	if scoped && !preImages {
+		return nil, ErrPreImageRequired
+	}
+	filter, err = d.tenantScope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	pipeline := watchPipeline(filter, opts.operationTypes, preImages)
+	streamOpts, err := streamOptions(ctx, opts, preImages)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := d.collectionOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Watch(ctx, pipeline, streamOpts)
+}
+
+// streamOptions 变更流选项，保存过 token 时从该 token 之后开始。
+// 使用 startAfter 而非 resumeAfter：invalidate 事件（集合被删除、重命名）的 token 只能用于 startAfter
+func streamOptions(ctx context.Context, opts *WatchOptions, preImages bool) (*options.ChangeStreamOptions, error) {
+	streamOpts := options.ChangeStream().SetFullDocument(opts.fullDocument)
+	if preImages {
+		streamOpts.SetFullDocumentBeforeChange(opts.beforeChange)
+	}
+	if nil != opts.batchSize {
+		streamOpts.SetBatchSize(*opts.batchSize)
+	}
+	if nil != opts.store {
+		token, err := opts.store.Load(ctx, opts.key)
+		if err != nil {
+			return nil, err
+		}
+		if nil != token {
+			streamOpts.SetStartAfter(token)
+		}
+	}
+	return streamOpts, nil
+}
+
+func consumeChanges[Entity interface{}](ctx context.Context, stream changeStream, opts *WatchOptions, handler func(ctx context.Context, event *ChangeEvent[Entity]) error) error {
+	for stream.Next(ctx) {
+		event := &ChangeEvent[Entity]{}
+		if err := stream.Decode(event); err != nil {
+			return err
+		}
+		if nil != event.FullDocument {
+			if err := afterLoad(ctx, event.FullDocument); err != nil {
+				return err
+			}
+		}
+		if err := handler(ctx, event); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+		if nil != opts.store {
+			if err := opts.store.Save(ctx, opts.key, event.ResumeToken); err != nil {
+				return err
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return stream.Err()
+}
+
+// watchPipeline 过滤条件的字段加上 fullDocument. 前缀；preImages 时删除事件按 fullDocumentBeforeChange 过滤，否则不受过滤条件限制
+func watchPipeline(filter interface{}, operationTypes []string, preImages bool) mongo.Pipeline {
+	pipeline := mongo.Pipeline{}
+	if len(operationTypes) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": operationTypes}}}})
+	}
+	if !isEmptyFilter(filter) {
+		deleted := interface{}(bson.M{"operationType": OperationDelete})
+		if preImages {
+			deleted = bson.M{"$and": bson.A{deleted, prefixFilter(filter, "fullDocumentBeforeChange.")}}
+		}
+		match := bson.M{"$or": bson.A{
+			bson.M{"operationType": OperationInvalidate},
+			deleted,
+			prefixFilter(filter, "fullDocument."),
+		}}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: match}})
+	}
+	return pipeline
+}
+
+// prefixFilter 为字段名加上前缀，$and、$or、$nor 中的条件递归处理
+func prefixFilter(filter interface{}, prefix string) interface{} {
+	switch f := filter.(type) {
+	case *QueryBuilder:
+		return prefixFilter(f.Build(), prefix)
+	case bson.M:
+		result := bson.M{}
+		for k, v := range f {
+			k, v = prefixField(k, v, prefix)
+			result[k] = v
+		}
+		return result
+	case map[string]interface{}:
+		return prefixFilter(bson.M(f), prefix)
+	case bson.D:
+		result := make(bson.D, 0, len(f))
+		for _, e := range f {
+			k, v := prefixField(e.Key, e.Value, prefix)
+			result = append(result, bson.E{Key: k, Value: v})
+		}
+		return result
+	}
+	return filter
+}
+
+func prefixField(key string, value interface{}, prefix string) (string, interface{}) {
+	if !strings.HasPrefix(key, "$") {
+		return prefix + key, value
+	}
+	switch key {
+	case "$and", "$or", "$nor":
+		if conditions, ok := value.(bson.A); ok {
+			result := make(bson.A, len(conditions))
+			for i, c := range conditions {
+				result[i] = prefixFilter(c, prefix)
+			}
+			return key, result
+		}
+		if conditions, ok := value.([]interface{}); ok {
+			return prefixField(key, bson.A(conditions), prefix)
+		}
+	}
+	return key, value
+}
+
+// MemoryResumeTokenStore 保存在内存中的 resume token，用于测试或单进程场景
+type MemoryResumeTokenStore struct {
+	tokens sync.Map
+}
+
+func NewMemoryResumeTokenStore() *MemoryResumeTokenStore {
+	return &MemoryResumeTokenStore{}
+}
+
+func (s *MemoryResumeTokenStore) Load(_ context.Context, key string) (bson.Raw, error) {
+	if v, ok := s.tokens.Load(key); ok {
+		return v.(bson.Raw), nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryResumeTokenStore) Save(_ context.Context, key string, token bson.Raw) error {
+	s.tokens.Store(key, token)
+	return nil
+}
+
+// NewCollectionResumeTokenStore resume token 保存在集合中，文档的 _id 为订阅者名称
+func NewCollectionResumeTokenStore(collection *mongo.Collection) *CollectionResumeTokenStore {
+	return &CollectionResumeTokenStore{collection: collection}
+}
+
+type CollectionResumeTokenStore struct {
+	collection *mongo.Collection
+}
+
+func (s *CollectionResumeTokenStore) Load(ctx context.Context, key string) (bson.Raw, error) {
+	var doc struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"_id": key}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+func (s *CollectionResumeTokenStore) Save(ctx context.Context, key string, token bson.Raw) error {
+	update := bson.M{"$set": bson.M{"token": token, "updated_at": time.Now()}}
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": key}, update, options.Update().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,174 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aomi-go/data/common/tenant"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeStream 以 bson 文档模拟变更流
+type fakeStream struct {
+	docs    []bson.Raw
+	current bson.Raw
+}
+
+func (s *fakeStream) Next(context.Context) bool {
+	if len(s.docs) == 0 {
+		return false
+	}
+	s.current, s.docs = s.docs[0], s.docs[1:]
+	return true
+}
+
+func (s *fakeStream) Decode(v interface{}) error { return bson.Unmarshal(s.current, v) }
+func (s *fakeStream) Err() error                 { return nil }
+func (s *fakeStream) Close(context.Context) error {
+	return nil
+}
+
+func newFakeStream(t *testing.T, events ...bson.M) *fakeStream {
+	s := &fakeStream{}
+	for _, e := range events {
+		raw, err := bson.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.docs = append(s.docs, raw)
+	}
+	return s
+}
+
+func testChangeEvents(t *testing.T) *fakeStream {
+	return newFakeStream(t,
+		bson.M{"_id": bson.M{"_data": "1"}, "operationType": OperationInsert, "fullDocument": bson.M{"name": "a"}, "documentKey": bson.M{"_id": "x"}},
+		bson.M{"_id": bson.M{"_data": "2"}, "operationType": OperationUpdate, "fullDocument": bson.M{"name": "b"}, "documentKey": bson.M{"_id": "x"},
+			"updateDescription": bson.M{"updatedFields": bson.M{"name": "b"}, "removedFields": bson.A{"age"}}},
+		bson.M{"_id": bson.M{"_data": "3"}, "operationType": OperationDelete, "documentKey": bson.M{"_id": "x"}},
+	)
+}
+
+func TestConsumeChanges(t *testing.T) {
+	store := NewMemoryResumeTokenStore()
+	opts := NewWatchOptions().SetResumeTokenStore(store, "reader")
+
+	var events []*ChangeEvent[User]
+	err := consumeChanges(context.TODO(), testChangeEvents(t), opts, func(_ context.Context, e *ChangeEvent[User]) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil || len(events) != 3 {
+		t.Fatalf("consumeChanges() = %d events, %v", len(events), err)
+	}
+	if events[0].FullDocument.Name != "a" || events[0].ID() != "x" {
+		t.Errorf("insert event = %+v", events[0])
+	}
+	if u := events[1].UpdateDescription; nil == u || u.UpdatedFields["name"] != "b" || u.RemovedFields[0] != "age" {
+		t.Errorf("update description = %+v", u)
+	}
+	if events[2].OperationType != OperationDelete || nil != events[2].FullDocument {
+		t.Errorf("delete event = %+v", events[2])
+	}
+	if token, _ := store.Load(context.TODO(), "reader"); token.Lookup("_data").StringValue() != "3" {
+		t.Errorf("saved token = %v", token)
+	}
+}
+
+func TestConsumeChangesInvalidate(t *testing.T) {
+	store := NewMemoryResumeTokenStore()
+	opts := NewWatchOptions().SetResumeTokenStore(store, "reader")
+	stream := newFakeStream(t,
+		bson.M{"_id": bson.M{"_data": "1"}, "operationType": OperationInsert, "fullDocument": bson.M{"name": "a"}, "documentKey": bson.M{"_id": "x"}},
+		bson.M{"_id": bson.M{"_data": "2"}, "operationType": OperationInvalidate},
+	)
+
+	var last *ChangeEvent[User]
+	err := consumeChanges(context.TODO(), stream, opts, func(_ context.Context, e *ChangeEvent[User]) error {
+		last = e
+		return nil
+	})
+	if err != nil || last.OperationType != OperationInvalidate {
+		t.Fatalf("consumeChanges() last = %+v, %v", last, err)
+	}
+
+	// invalidate 的 token 只能通过 startAfter 重新订阅
+	streamOpts, err := streamOptions(context.TODO(), opts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nil != streamOpts.ResumeAfter {
+		t.Errorf("ResumeAfter = %v, want nil", streamOpts.ResumeAfter)
+	}
+	if token, ok := streamOpts.StartAfter.(bson.Raw); !ok || token.Lookup("_data").StringValue() != "2" {
+		t.Errorf("StartAfter = %v", streamOpts.StartAfter)
+	}
+}
+
+func TestConsumeChangesStop(t *testing.T) {
+	store := NewMemoryResumeTokenStore()
+	opts := NewWatchOptions().SetResumeTokenStore(store, "reader")
+
+	boom := errors.New("boom")
+	n := 0
+	err := consumeChanges(context.TODO(), testChangeEvents(t), opts, func(_ context.Context, e *ChangeEvent[User]) error {
+		if n++; n == 2 {
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("consumeChanges() error = %v, want boom", err)
+	}
+	// 处理失败的事件不保存 token
+	if token, _ := store.Load(context.TODO(), "reader"); token.Lookup("_data").StringValue() != "1" {
+		t.Errorf("saved token = %v", token)
+	}
+
+	n = 0
+	err = consumeChanges(context.TODO(), testChangeEvents(t), opts, func(_ context.Context, e *ChangeEvent[User]) error {
+		n++
+		return ErrStopIteration
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("consumeChanges() with stop = %d, %v", n, err)
+	}
+}
+
+func TestWatchPipeline(t *testing.T) {
+	if pipeline := watchPipeline(nil, nil, false); len(pipeline) != 0 {
+		t.Errorf("watchPipeline() = %v", pipeline)
+	}
+
+	filter := NewQueryBuilder().Is("name", "a").Or(bson.M{"age": bson.M{"$gt": 3}}, bson.M{"vip": true}).Build()
+	pipeline := watchPipeline(filter, []string{OperationInsert, OperationUpdate}, false)
+	if len(pipeline) != 2 {
+		t.Fatalf("watchPipeline() = %v", pipeline)
+	}
+	match := pipeline[1][0].Value.(bson.M)["$or"].(bson.A)[2].(bson.M)
+	if match["fullDocument.name"] != "a" {
+		t.Errorf("prefixed filter = %v", match)
+	}
+	or := match["$or"].(bson.A)
+	if _, ok := or[0].(bson.M)["fullDocument.age"]; !ok {
+		t.Errorf("prefixed $or = %v", or)
+	}
+}
+
+func TestWatchPipelinePreImages(t *testing.T) {
+	pipeline := watchPipeline(bson.M{TenantIdField: "t1"}, nil, true)
+	or := pipeline[0][0].Value.(bson.M)["$or"].(bson.A)
+	deleted := or[1].(bson.M)["$and"].(bson.A)
+	if deleted[0].(bson.M)["operationType"] != OperationDelete || deleted[1].(bson.M)["fullDocumentBeforeChange."+TenantIdField] != "t1" {
+		t.Errorf("delete match = %v", deleted)
+	}
+}
+
+func TestWatchTenantRequiresPreImages(t *testing.T) {
+	repo := (&DocumentRepository[User]{}).SetMultiTenant(true)
+	ctx := tenant.WithTenant(context.TODO(), "t1")
+	if _, err := repo.watch(ctx, NewWatchOptions()); !errors.Is(err, ErrPreImageRequired) {
+		t.Errorf("watch() without pre-images error = %v", err)
+	}
+}
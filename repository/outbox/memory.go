@@ -0,0 +1,151 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryStore 保存在内存中的消息，用于测试
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{messages: map[primitive.ObjectID]*memoryMessage{}, sequences: map[string]int64{}}
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	messages  map[primitive.ObjectID]*memoryMessage
+	sequences map[string]int64
+}
+
+type memoryMessage struct {
+	Message
+	lockedUntil time.Time
+}
+
+func (s *MemoryStore) Add(_ context.Context, messages ...*Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prepare(messages, time.Now())
+	for _, m := range messages {
+		if "" != m.Key {
+			s.sequences[m.Key]++
+			m.Seq = s.sequences[m.Key]
+		}
+		s.messages[m.ID] = &memoryMessage{Message: *m}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Pending(_ context.Context, now time.Time, limit int) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blocked := map[string]bool{}
+	first := map[string]*memoryMessage{}
+	for _, m := range s.messages {
+		if StatusPending != m.Status || "" == m.Key {
+			continue
+		}
+		if m.NextAttemptAt.After(now) {
+			blocked[m.Key] = true
+		}
+		if head, ok := first[m.Key]; !ok || m.Seq < head.Seq {
+			first[m.Key] = m
+		}
+	}
+	result := make([]*Message, 0)
+	for _, m := range s.messages {
+		if StatusPending == m.Status && !m.NextAttemptAt.After(now) && !blocked[m.Key] {
+			copied := m.Message
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID.Hex() < result[j].ID.Hex() })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	heads := map[string]*Message{}
+	for _, m := range result {
+		if head, ok := first[m.Key]; ok {
+			copied := head.Message
+			heads[m.Key] = &copied
+		}
+	}
+	return inOrder(result, heads), nil
+}
+
+func (s *MemoryStore) Claim(_ context.Context, id primitive.ObjectID, _ string, now time.Time, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.messages[id]
+	if !ok || StatusPending != m.Status || m.lockedUntil.After(now) {
+		return false, nil
+	}
+	m.lockedUntil = until
+	return true, nil
+}
+
+func (s *MemoryStore) Done(_ context.Context, id primitive.ObjectID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.messages[id]; ok {
+		m.Status = StatusDone
+		m.PublishedAt = &at
+		m.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Retry(_ context.Context, retry *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.messages[retry.ID]; ok {
+		m.Status = retry.Status
+		m.Attempts = retry.Attempts
+		m.LastError = retry.LastError
+		m.NextAttemptAt = retry.NextAttemptAt
+		m.lockedUntil = time.Time{}
+	}
+	return nil
+}
+
+// Get 返回消息的副本
+func (s *MemoryStore) Get(id primitive.ObjectID) (*Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.messages[id]
+	if !ok {
+		return nil, false
+	}
+	copied := m.Message
+	return &copied, true
+}
+
+// MemoryPublisher 记录发送的消息，用于测试
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []*Message
+	// Fail 不为空时返回的错误作为发送失败
+	Fail func(m *Message) error
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, m *Message) error {
+	if nil != p.Fail {
+		if err := p.Fail(m); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+// Messages 已发送的消息
+func (p *MemoryPublisher) Messages() []*Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Message{}, p.messages...)
+}
@@ -0,0 +1,306 @@
+// Package outbox 事务性发件箱：业务写入与待发送的事件在同一个事务中提交，再由 Relay 投递到消息中间件，
+// 保证事件至少投递一次，无需在 MongoDB 与消息中间件之间做两阶段提交。
+//
+// 相同 Key 的消息按事务提交的顺序发送：写入时在事务中递增 Key 的序号，
+// 并发写入同一 Key 的事务会冲突并重试，序号的顺序即提交的顺序。不在事务中写入时只保证单个进程内的顺序。
+//
+//	err := outbox.WithTransaction(ctx, store, func(ctx mongo.SessionContext) ([]*outbox.Message, error) {
+//		if _, err := orders.Save(ctx, order); err != nil {
+//			return nil, err
+//		}
+//		msg, err := outbox.NewMessage("order.created", order.ID.String(), order)
+//		return []*outbox.Message{msg}, err
+//	})
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	mongox "github.com/aomi-go/data/repository/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// StatusPending 待发送
+	StatusPending = "pending"
+	// StatusDone 已发送
+	StatusDone = "done"
+	// StatusFailed 超过最大重试次数，不再发送
+	StatusFailed = "failed"
+)
+
+// Message 出站消息
+type Message struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Topic string             `bson:"topic"`
+	// Key 相同 Key 的消息按提交顺序发送，为空时不保证顺序
+	Key string `bson:"key"`
+	// Seq Key 内的序号，写入时分配
+	Seq     int64             `bson:"seq"`
+	Payload []byte            `bson:"payload"`
+	Headers map[string]string `bson:"headers,omitempty"`
+
+	Status        string     `bson:"status"`
+	Attempts      int        `bson:"attempts"`
+	LastError     string     `bson:"last_error,omitempty"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at"`
+	CreatedAt     time.Time  `bson:"created_at"`
+	PublishedAt   *time.Time `bson:"published_at,omitempty"`
+}
+
+// NewMessage 创建消息，payload 编码为 JSON
+func NewMessage(topic string, key string, payload interface{}) (*Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Topic: topic, Key: key, Payload: data}, nil
+}
+
+// Store 出站消息的存储
+type Store interface {
+	// Add 写入待发送的消息，ctx 为事务的 SessionContext 时随事务一同提交
+	Add(ctx context.Context, messages ...*Message) error
+	// Pending 返回 now 时已到重试时间的待发送消息，同一 Key 的消息按 Seq 排序且从该 Key 最早的待发送消息开始连续，
+	// 同一 Key 中有消息处于退避等待时，该 Key 后续的消息也不返回。为补上各 Key 最早的消息，返回的数量可能略多于 limit
+	Pending(ctx context.Context, now time.Time, limit int) ([]*Message, error)
+	// Claim 锁定消息直到 until，消息在 now 时仍被其他 Relay 锁定或不再待发送时返回 false
+	Claim(ctx context.Context, id primitive.ObjectID, owner string, now time.Time, until time.Time) (bool, error)
+	// Done 标记为已发送
+	Done(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// Retry 保存重试信息（Status、Attempts、LastError、NextAttemptAt）并释放锁
+	Retry(ctx context.Context, m *Message) error
+}
+
+// WithTransaction 在 mongox.WithTransaction 中执行 fn，并将 fn 返回的消息写入 store，与 fn 中的写入一同提交
+func WithTransaction(ctx context.Context, store Store, fn func(ctx mongo.SessionContext) ([]*Message, error), opts ...*options.SessionOptions) error {
+	_, err := mongox.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		messages, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			return nil, nil
+		}
+		return nil, store.Add(ctx, messages...)
+	}, opts...)
+	return err
+}
+
+// prepare 补全新消息的 ID、状态与时间
+func prepare(messages []*Message, now time.Time) {
+	for _, m := range messages {
+		if m.ID.IsZero() {
+			m.ID = primitive.NewObjectID()
+		}
+		m.Status = StatusPending
+		m.CreatedAt = now
+		m.NextAttemptAt = now
+	}
+}
+
+// inOrder 补上 messages 中各 Key 最早的待发送消息 heads，按 Seq 排序，每个 Key 只保留从最早的消息开始连续的消息
+func inOrder(messages []*Message, heads map[string]*Message) []*Message {
+	included := make(map[primitive.ObjectID]bool, len(messages))
+	for _, m := range messages {
+		included[m.ID] = true
+	}
+	next := make(map[string]int64, len(heads))
+	for key, head := range heads {
+		if !included[head.ID] {
+			messages = append(messages, head)
+		}
+		next[key] = head.Seq
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Seq < messages[j].Seq })
+
+	result := make([]*Message, 0, len(messages))
+	for _, m := range messages {
+		if "" != m.Key {
+			if m.Seq != next[m.Key] {
+				continue
+			}
+			next[m.Key]++
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// NewMongoStore 消息保存在 collection 中，Key 的序号保存在 <collection>_seq 集合中。
+// 在事务中写入时两个集合需要预先创建
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{
+		collection: collection,
+		sequences:  collection.Database().Collection(collection.Name() + "_seq"),
+	}
+}
+
+type MongoStore struct {
+	collection *mongo.Collection
+	sequences  *mongo.Collection
+}
+
+func (s *MongoStore) Add(ctx context.Context, messages ...*Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	prepare(messages, time.Now())
+	if err := s.sequence(ctx, messages); err != nil {
+		return err
+	}
+	docs := make([]interface{}, len(messages))
+	for i, m := range messages {
+		docs[i] = m
+	}
+	_, err := s.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// sequence 递增每个 Key 的序号并分配给消息
+func (s *MongoStore) sequence(ctx context.Context, messages []*Message) error {
+	counts := map[string]int64{}
+	keys := make([]string, 0)
+	for _, m := range messages {
+		if "" == m.Key {
+			continue
+		}
+		if _, ok := counts[m.Key]; !ok {
+			keys = append(keys, m.Key)
+		}
+		counts[m.Key]++
+	}
+	next := make(map[string]int64, len(keys))
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	for _, key := range keys {
+		var counter struct {
+			Seq int64 `bson:"seq"`
+		}
+		err := s.sequences.FindOneAndUpdate(ctx, bson.M{"_id": key}, bson.M{"$inc": bson.M{"seq": counts[key]}}, opts).Decode(&counter)
+		if err != nil {
+			return err
+		}
+		next[key] = counter.Seq - counts[key] + 1
+	}
+	for _, m := range messages {
+		if "" != m.Key {
+			m.Seq = next[m.Key]
+			next[m.Key]++
+		}
+	}
+	return nil
+}
+
+func (s *MongoStore) Pending(ctx context.Context, now time.Time, limit int) ([]*Message, error) {
+	// 处于退避等待的 Key
+	blocked, err := s.collection.Distinct(ctx, "key", bson.M{
+		"status":          StatusPending,
+		"key":             bson.M{"$ne": ""},
+		"next_attempt_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"status": StatusPending, "next_attempt_at": bson.M{"$lte": now}}
+	if len(blocked) > 0 {
+		filter["key"] = bson.M{"$nin": blocked}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0)
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	heads, err := s.heads(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(messages, heads), nil
+}
+
+// heads messages 中每个 Key 最早的待发送消息
+func (s *MongoStore) heads(ctx context.Context, messages []*Message) (map[string]*Message, error) {
+	keys := bson.A{}
+	for _, m := range messages {
+		if "" != m.Key {
+			keys = append(keys, m.Key)
+		}
+	}
+	heads := map[string]*Message{}
+	if len(keys) == 0 {
+		return heads, nil
+	}
+	cursor, err := s.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": StatusPending, "key": bson.M{"$in": keys}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "seq", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$key", "head": bson.M{"$first": "$$ROOT"}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var results []struct {
+		Head *Message `bson:"head"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		heads[r.Head.Key] = r.Head
+	}
+	return heads, nil
+}
+
+func (s *MongoStore) Claim(ctx context.Context, id primitive.ObjectID, owner string, now time.Time, until time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":    id,
+		"status": StatusPending,
+		"$or":    bson.A{bson.M{"locked_until": nil}, bson.M{"locked_until": bson.M{"$lt": now}}},
+	}
+	update := bson.M{"$set": bson.M{"locked_by": owner, "locked_until": until}}
+	r, err := s.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return r.ModifiedCount > 0, nil
+}
+
+func (s *MongoStore) Done(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{
+		"$set":   bson.M{"status": StatusDone, "published_at": at},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+	_, err := s.collection.UpdateByID(ctx, id, update)
+	return err
+}
+
+func (s *MongoStore) Retry(ctx context.Context, m *Message) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":          m.Status,
+			"attempts":        m.Attempts,
+			"last_error":      m.LastError,
+			"next_attempt_at": m.NextAttemptAt,
+		},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+	_, err := s.collection.UpdateByID(ctx, m.ID, update)
+	return err
+}
+
+// EnsureIndexes 创建 Pending 查询使用的索引
+func (s *MongoStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "key", Value: 1}, {Key: "seq", Value: 1}}},
+	})
+	return err
+}
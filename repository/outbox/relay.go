@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Publisher 将消息发送到消息中间件，返回 nil 表示发送成功
+type Publisher interface {
+	Publish(ctx context.Context, m *Message) error
+}
+
+// PublisherFunc 函数形式的 Publisher
+type PublisherFunc func(ctx context.Context, m *Message) error
+
+func (f PublisherFunc) Publish(ctx context.Context, m *Message) error {
+	return f(ctx, m)
+}
+
+// ExponentialBackoff 第 n 次失败后等待 base * 2^(n-1)，不超过 max
+func ExponentialBackoff(base time.Duration, max time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		d := base
+		for i := 1; i < attempts && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// NewRelay 创建消息投递器
+func NewRelay(store Store, publisher Publisher) *Relay {
+	host, _ := os.Hostname()
+	return &Relay{
+		store:        store,
+		publisher:    publisher,
+		owner:        fmt.Sprintf("%s-%d", host, os.Getpid()),
+		batchSize:    100,
+		pollInterval: time.Second,
+		lease:        time.Minute,
+		backoff:      ExponentialBackoff(time.Second, 5*time.Minute),
+		clock:        time.Now,
+	}
+}
+
+// Relay 读取待发送的消息交给 Publisher，成功后标记为已发送，失败时按退避策略重试。
+// 同一 Key 的消息在前一条发送成功（或超过最大重试次数）前不会发送，多个 Relay 通过锁定消息避免重复发送
+type Relay struct {
+	store        Store
+	publisher    Publisher
+	owner        string
+	batchSize    int
+	pollInterval time.Duration
+	// lease 锁定消息的时长，需大于一次发送的耗时
+	lease       time.Duration
+	backoff     func(attempts int) time.Duration
+	maxAttempts int
+	onError     func(err error)
+	clock       func() time.Time
+}
+
+// SetBatchSize 每次读取的消息数量
+func (r *Relay) SetBatchSize(size int) *Relay {
+	r.batchSize = size
+	return r
+}
+
+// SetPollInterval 没有待发送的消息时的轮询间隔
+func (r *Relay) SetPollInterval(interval time.Duration) *Relay {
+	r.pollInterval = interval
+	return r
+}
+
+// SetLease 锁定消息的时长
+func (r *Relay) SetLease(lease time.Duration) *Relay {
+	r.lease = lease
+	return r
+}
+
+// SetBackoff 重试的等待时间，默认为 ExponentialBackoff(time.Second, 5*time.Minute)
+func (r *Relay) SetBackoff(backoff func(attempts int) time.Duration) *Relay {
+	r.backoff = backoff
+	return r
+}
+
+// SetMaxAttempts 最大发送次数，超过后标记为 StatusFailed，<= 0 表示一直重试
+func (r *Relay) SetMaxAttempts(n int) *Relay {
+	r.maxAttempts = n
+	return r
+}
+
+// SetErrorHandler Run 中读写存储的错误交给 handler，Run 会在下一个轮询周期继续
+func (r *Relay) SetErrorHandler(handler func(err error)) *Relay {
+	r.onError = handler
+	return r
+}
+
+// Run 持续投递消息直到 ctx 结束
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		published, err := r.RelayOnce(ctx)
+		if err != nil && nil != r.onError {
+			r.onError(err)
+		}
+		// 整批发送成功时立即读取下一批，否则等待轮询间隔，避免发送失败时空转
+		if nil == err && published >= r.batchSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// RelayOnce 处理一批消息，返回发送成功的消息数量
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.store.Pending(ctx, r.clock(), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// blocked 本批中不能发送的 Key，保证同一 Key 的消息按顺序发送
+	blocked := map[string]bool{}
+	published := 0
+	for _, m := range messages {
+		if "" != m.Key && blocked[m.Key] {
+			continue
+		}
+		sent, err := r.relay(ctx, m)
+		if err != nil {
+			return published, err
+		}
+		if sent {
+			published++
+		} else {
+			blocked[m.Key] = true
+		}
+	}
+	return published, nil
+}
+
+// relay 发送一条消息，未到重试时间、被其他 Relay 锁定或发送失败时返回 false
+func (r *Relay) relay(ctx context.Context, m *Message) (bool, error) {
+	now := r.clock()
+	if m.NextAttemptAt.After(now) {
+		return false, nil
+	}
+	claimed, err := r.store.Claim(ctx, m.ID, r.owner, now, now.Add(r.lease))
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	if err := r.publisher.Publish(ctx, m); err != nil {
+		m.Attempts++
+		m.LastError = err.Error()
+		if r.maxAttempts > 0 && m.Attempts >= r.maxAttempts {
+			m.Status = StatusFailed
+		} else {
+			m.NextAttemptAt = now.Add(r.backoff(m.Attempts))
+		}
+		return false, r.store.Retry(ctx, m)
+	}
+	return true, r.store.Done(ctx, m.ID, now)
+}
@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func addMessages(t *testing.T, store Store, keys ...string) []*Message {
+	messages := make([]*Message, len(keys))
+	for i, key := range keys {
+		m, err := NewMessage("order.created", key, map[string]int{"seq": i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages[i] = m
+	}
+	if err := store.Add(context.TODO(), messages...); err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestRelayOnce(t *testing.T) {
+	store := NewMemoryStore()
+	messages := addMessages(t, store, "a", "b", "a")
+	publisher := &MemoryPublisher{}
+
+	n, err := NewRelay(store, publisher).RelayOnce(context.TODO())
+	if err != nil || n != 3 {
+		t.Fatalf("RelayOnce() = %d, %v", n, err)
+	}
+	published := publisher.Messages()
+	if len(published) != 3 || published[0].ID != messages[0].ID || published[2].ID != messages[2].ID {
+		t.Fatalf("published = %v", published)
+	}
+	if m, _ := store.Get(messages[0].ID); StatusDone != m.Status || nil == m.PublishedAt {
+		t.Errorf("message after publish = %+v", m)
+	}
+	if pending, _ := store.Pending(context.TODO(), time.Now(), 10); len(pending) != 0 {
+		t.Errorf("pending = %v", pending)
+	}
+}
+
+func TestRelayRetry(t *testing.T) {
+	store := NewMemoryStore()
+	messages := addMessages(t, store, "a", "b", "a")
+	boom := errors.New("boom")
+	publisher := &MemoryPublisher{Fail: func(m *Message) error {
+		if m.ID == messages[0].ID {
+			return boom
+		}
+		return nil
+	}}
+	now := time.Now()
+	relay := NewRelay(store, publisher).SetBackoff(ExponentialBackoff(time.Minute, time.Hour))
+	relay.clock = func() time.Time { return now }
+
+	if _, err := relay.RelayOnce(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	// a 的第一条失败后，a 的后续消息不能越过它
+	if published := publisher.Messages(); len(published) != 1 || published[0].ID != messages[1].ID {
+		t.Fatalf("published = %v", published)
+	}
+	m, _ := store.Get(messages[0].ID)
+	if m.Attempts != 1 || m.LastError != "boom" || !m.NextAttemptAt.Equal(now.Add(time.Minute)) || StatusPending != m.Status {
+		t.Fatalf("failed message = %+v", m)
+	}
+
+	// 未到重试时间
+	if _, err := relay.RelayOnce(context.TODO()); err != nil || len(publisher.Messages()) != 1 {
+		t.Fatalf("RelayOnce() before backoff published %d, %v", len(publisher.Messages()), err)
+	}
+
+	publisher.Fail = nil
+	now = now.Add(time.Minute)
+	if _, err := relay.RelayOnce(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if published := publisher.Messages(); len(published) != 3 || published[1].ID != messages[0].ID || published[2].ID != messages[2].ID {
+		t.Fatalf("published after retry = %v", published)
+	}
+}
+
+func TestRelayBackoffBatch(t *testing.T) {
+	store := NewMemoryStore()
+	failed := addMessages(t, store, "a", "b", "c")
+	publisher := &MemoryPublisher{Fail: func(m *Message) error {
+		for _, f := range failed {
+			if m.ID == f.ID {
+				return errors.New("boom")
+			}
+		}
+		return nil
+	}}
+	now := time.Now()
+	relay := NewRelay(store, publisher).SetBatchSize(2).SetBackoff(ExponentialBackoff(time.Minute, time.Hour))
+	relay.clock = func() time.Time { return now }
+
+	// 发送失败的消息不计入发送数量，Run 不会立即读取下一批
+	for i := 0; i < 2; i++ {
+		if n, err := relay.RelayOnce(context.TODO()); err != nil || n != 0 {
+			t.Fatalf("RelayOnce() = %d, %v", n, err)
+		}
+	}
+
+	// 退避中的消息超过 batchSize 时，其他 Key 的新消息仍能发送，退避中 Key 的后续消息不发送
+	messages := addMessages(t, store, "a", "d")
+	now = time.Now()
+	if n, err := relay.RelayOnce(context.TODO()); err != nil || n != 1 {
+		t.Fatalf("RelayOnce() = %d, %v", n, err)
+	}
+	if published := publisher.Messages(); len(published) != 1 || published[0].ID != messages[1].ID {
+		t.Fatalf("published = %v", published)
+	}
+}
+
+func TestPendingOrder(t *testing.T) {
+	store := NewMemoryStore()
+	// 后提交的消息 ID 更小，仍按提交顺序返回
+	first, _ := NewMessage("order.created", "a", 1)
+	first.ID = primitive.NewObjectIDFromTimestamp(time.Now().Add(time.Second))
+	second, _ := NewMessage("order.created", "a", 2)
+	second.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+	_ = store.Add(context.TODO(), first)
+	_ = store.Add(context.TODO(), second)
+	if first.Seq != 1 || second.Seq != 2 {
+		t.Fatalf("seq = %d, %d", first.Seq, second.Seq)
+	}
+
+	pending, err := store.Pending(context.TODO(), time.Now(), 10)
+	if err != nil || len(pending) != 2 || pending[0].ID != first.ID || pending[1].ID != second.ID {
+		t.Fatalf("Pending() = %v, %v", pending, err)
+	}
+	// 批次中只有后提交的消息时补上 Key 最早的消息
+	pending, err = store.Pending(context.TODO(), time.Now(), 1)
+	if err != nil || len(pending) != 2 || pending[0].ID != first.ID {
+		t.Fatalf("Pending() with limit = %v, %v", pending, err)
+	}
+}
+
+func TestRelayMaxAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	messages := addMessages(t, store, "a", "a")
+	publisher := &MemoryPublisher{Fail: func(m *Message) error {
+		if m.ID == messages[0].ID {
+			return errors.New("boom")
+		}
+		return nil
+	}}
+	relay := NewRelay(store, publisher).SetMaxAttempts(1)
+
+	for i := 0; i < 2; i++ {
+		if _, err := relay.RelayOnce(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if m, _ := store.Get(messages[0].ID); StatusFailed != m.Status {
+		t.Errorf("message status = %s, want failed", m.Status)
+	}
+	if published := publisher.Messages(); len(published) != 1 || published[0].ID != messages[1].ID {
+		t.Errorf("published = %v", published)
+	}
+}
+
+func TestRelayClaim(t *testing.T) {
+	store := NewMemoryStore()
+	messages := addMessages(t, store, "a")
+	now := time.Now()
+	if ok, _ := store.Claim(context.TODO(), messages[0].ID, "other", now, now.Add(time.Minute)); !ok {
+		t.Fatal("Claim() = false")
+	}
+	publisher := &MemoryPublisher{}
+	relay := NewRelay(store, publisher)
+	relay.clock = func() time.Time { return now }
+	if _, err := relay.RelayOnce(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.Messages()) != 0 {
+		t.Error("published message claimed by another relay")
+	}
+
+	// 锁是否过期按 Relay 的时钟判断
+	relay.clock = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, err := relay.RelayOnce(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.Messages()) != 1 {
+		t.Error("message with an expired lease was not published")
+	}
+}
+
+func TestRun(t *testing.T) {
+	store := NewMemoryStore()
+	addMessages(t, store, "a", "b")
+	ctx, cancel := context.WithCancel(context.TODO())
+	publisher := &MemoryPublisher{}
+	publisher.Fail = func(*Message) error {
+		if len(publisher.Messages()) == 1 {
+			cancel()
+		}
+		return nil
+	}
+
+	err := NewRelay(store, publisher).SetPollInterval(time.Millisecond).Run(ctx)
+	if !errors.Is(err, context.Canceled) || len(publisher.Messages()) != 2 {
+		t.Fatalf("Run() = %v, published %d", err, len(publisher.Messages()))
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 10*time.Second)
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
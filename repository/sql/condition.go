@@ -0,0 +1,27 @@
+package sql
+
+import "github.com/aomi-go/data/repository/criteria"
+
+// Where 构建查询条件，clause 中使用 ? 作为占位符，由 Dialect 负责改写
+func Where(clause string, args ...interface{}) *Condition {
+	return &Condition{Clause: clause, Args: args}
+}
+
+// WhereCriteria 将 criteria 条件编译为查询条件，列名按 dialect 引用
+func WhereCriteria(dialect Dialect, c *criteria.Criteria) (*Condition, error) {
+	clause, args, err := c.ToSQL(dialect.Quote)
+	if err != nil {
+		return nil, err
+	}
+	return Where(clause, args...), nil
+}
+
+// Condition WHERE 子句及其参数，nil 或空 Clause 表示不过滤
+type Condition struct {
+	Clause string
+	Args   []interface{}
+}
+
+func (c *Condition) isEmpty() bool {
+	return nil == c || "" == c.Clause
+}
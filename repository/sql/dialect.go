@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect 屏蔽不同数据库在占位符、标识符引用上的差异
+type Dialect interface {
+	// Placeholder 返回第 n 个（从 1 开始）参数的占位符
+	Placeholder(n int) string
+	// Quote 引用表名、列名
+	Quote(identifier string) string
+	// Returning 返回插入后取回自增主键的子句，不支持时返回空字符串并使用 LastInsertId
+	Returning(column string) string
+	// Upsert 返回追加在 INSERT 之后的子句：主键 pk 冲突时以插入的值更新 columns，pk 与 columns 均已引用
+	Upsert(pk string, columns []string) string
+}
+
+var (
+	SQLite   Dialect = sqliteDialect{}
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+)
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+func (sqliteDialect) Quote(identifier string) string { return quote(identifier, '"') }
+
+func (sqliteDialect) Returning(column string) string { return "" }
+
+func (sqliteDialect) Upsert(pk string, columns []string) string { return onConflict(pk, columns) }
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+
+func (mysqlDialect) Quote(identifier string) string { return quote(identifier, '`') }
+
+func (mysqlDialect) Returning(column string) string { return "" }
+
+func (mysqlDialect) Upsert(pk string, columns []string) string {
+	if len(columns) == 0 {
+		return " ON DUPLICATE KEY UPDATE " + pk + " = " + pk
+	}
+	sets := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = c + " = VALUES(" + c + ")"
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) Quote(identifier string) string { return quote(identifier, '"') }
+
+func (d postgresDialect) Returning(column string) string { return " RETURNING " + d.Quote(column) }
+
+func (postgresDialect) Upsert(pk string, columns []string) string { return onConflict(pk, columns) }
+
+// onConflict SQLite 与 PostgreSQL 的 ON CONFLICT 子句
+func onConflict(pk string, columns []string) string {
+	if len(columns) == 0 {
+		return " ON CONFLICT (" + pk + ") DO NOTHING"
+	}
+	sets := make([]string, len(columns))
+	for i, c := range columns {
+		sets[i] = c + " = excluded." + c
+	}
+	return " ON CONFLICT (" + pk + ") DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+func quote(identifier string, q byte) string {
+	s := string(q)
+	return s + strings.ReplaceAll(identifier, s, s+s) + s
+}
+
+// rebind 将 ? 占位符按方言改写，start 为第一个占位符的序号
+func rebind(d Dialect, clause string, start int) string {
+	if d.Placeholder(1) == "?" {
+		return clause
+	}
+	var b strings.Builder
+	n := start
+	for _, c := range clause {
+		if c == '?' {
+			b.WriteString(d.Placeholder(n))
+			n++
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,338 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/page"
+	"github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/repository"
+)
+
+var _ repository.CrudRepository[struct{ ID int64 }] = (*Repository[struct{ ID int64 }])(nil)
+
+// NewRepositoryWithEntity creates a new Repository, 表名由 emptyEntity 推导（见 TableEntity）
+func NewRepositoryWithEntity[E interface{}](db *sql.DB, dialect Dialect, emptyEntity E) *Repository[E] {
+	return NewRepository[E](db, dialect, tableName(&emptyEntity))
+}
+
+// NewRepository creates a new Repository.
+func NewRepository[E interface{}](db *sql.DB, dialect Dialect, tableName string) *Repository[E] {
+	return &Repository[E]{
+		db:        db,
+		dialect:   dialect,
+		tableName: tableName,
+	}
+}
+
+// Repository 基于 database/sql 的 repository.CrudRepository 实现，字段通过 db 标签映射到列
+type Repository[Entity interface{}] struct {
+	db        *sql.DB
+	dialect   Dialect
+	tableName string
+}
+
+// executor 由 *sql.DB 与 *sql.Tx 实现
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *Repository[Entity]) Save(ctx context.Context, entity *Entity) (*Entity, error) {
+	if err := r.save(ctx, r.db, entity); err != nil {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// SaveMany 在同一事务中保存多个实体
+func (r *Repository[Entity]) SaveMany(ctx context.Context, entities []*Entity) ([]*Entity, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities {
+		if err := r.save(ctx, tx, entity); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+func (r *Repository[Entity]) FindAll(ctx context.Context) ([]*Entity, error) {
+	return r.Find(ctx, nil)
+}
+
+func (r *Repository[Entity]) FindAllById(ctx context.Context, ids ...interface{}) ([]*Entity, error) {
+	if len(ids) == 0 {
+		return []*Entity{}, nil
+	}
+	tb, err := r.table()
+	if err != nil {
+		return nil, err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	clause := fmt.Sprintf("%s IN (%s)", r.dialect.Quote(tb.pk.name), placeholders)
+	return r.Find(ctx, Where(clause, ids...))
+}
+
+func (r *Repository[Entity]) FindById(ctx context.Context, id interface{}) (*Entity, error) {
+	filter, err := r.idCondition(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindOne(ctx, filter)
+}
+
+func (r *Repository[Entity]) ExistsById(ctx context.Context, id interface{}) (bool, error) {
+	filter, err := r.idCondition(id)
+	if err != nil {
+		return false, err
+	}
+	return r.Exist(ctx, filter)
+}
+
+func (r *Repository[Entity]) DeleteById(ctx context.Context, id interface{}) (bool, error) {
+	filter, err := r.idCondition(id)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.Delete(ctx, filter)
+	return n > 0, err
+}
+
+// Find 根据条件查询数据
+func (r *Repository[Entity]) Find(ctx context.Context, filter *Condition) ([]*Entity, error) {
+	return r.query(ctx, filter, nil, nil)
+}
+
+// FindOne 查找单条数据，不存在时返回 common.ErrNoResult
+func (r *Repository[Entity]) FindOne(ctx context.Context, filter *Condition) (*Entity, error) {
+	result, err := r.query(ctx, filter, nil, page.NewPageable(0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, common.ErrNoResult
+	}
+	return result[0], nil
+}
+
+// Count 统计数据
+func (r *Repository[Entity]) Count(ctx context.Context, filter *Condition) (int64, error) {
+	query, args := r.where(fmt.Sprintf("SELECT COUNT(*) FROM %s", r.dialect.Quote(r.tableName)), filter)
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Exist 存在
+func (r *Repository[Entity]) Exist(ctx context.Context, filter *Condition) (bool, error) {
+	if count, err := r.Count(ctx, filter); nil == err {
+		return count > 0, nil
+	} else {
+		return false, err
+	}
+}
+
+// Delete 根据条件删除数据，返回删除的行数
+func (r *Repository[Entity]) Delete(ctx context.Context, filter *Condition) (int64, error) {
+	query, args := r.where(fmt.Sprintf("DELETE FROM %s", r.dialect.Quote(r.tableName)), filter)
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// QueryWithPage 分页查询
+func (r *Repository[Entity]) QueryWithPage(ctx context.Context, filter *Condition, pageable *page.Pageable) (*page.Page[Entity], error) {
+	if nil == pageable {
+		pageable = page.NewDefaultPageable()
+	}
+	total, err := r.Count(ctx, filter)
+	if nil != err {
+		return nil, err
+	}
+
+	if total == 0 {
+		return page.NewPage[Entity](make([]*Entity, 0), 0, pageable), nil
+	}
+
+	entities, err := r.query(ctx, filter, &pageable.Sort, pageable)
+	if nil != err {
+		return nil, err
+	}
+
+	return page.NewPage[Entity](entities, total, pageable), nil
+}
+
+// QueryWithSort 排序查询
+func (r *Repository[Entity]) QueryWithSort(ctx context.Context, filter *Condition, sort *sort.Sort) ([]*Entity, error) {
+	return r.query(ctx, filter, sort, nil)
+}
+
+func (r *Repository[Entity]) table() (*table, error) {
+	var empty Entity
+	return tableOf(reflect.TypeOf(empty))
+}
+
+func (r *Repository[Entity]) idCondition(id interface{}) (*Condition, error) {
+	tb, err := r.table()
+	if err != nil {
+		return nil, err
+	}
+	return Where(r.dialect.Quote(tb.pk.name)+" = ?", id), nil
+}
+
+// where 拼接 WHERE 子句并按方言改写占位符
+func (r *Repository[Entity]) where(query string, filter *Condition) (string, []interface{}) {
+	if filter.isEmpty() {
+		return query, nil
+	}
+	return query + " WHERE " + rebind(r.dialect, filter.Clause, 1), filter.Args
+}
+
+func (r *Repository[Entity]) query(ctx context.Context, filter *Condition, s *sort.Sort, pageable *page.Pageable) ([]*Entity, error) {
+	tb, err := r.table()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(tb.columns))
+	for i, c := range tb.columns {
+		names[i] = r.dialect.Quote(c.name)
+	}
+	query, args := r.where(fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), r.dialect.Quote(r.tableName)), filter)
+
+	if nil != s {
+		orderBy, err := r.orderBy(tb, *s)
+		if err != nil {
+			return nil, err
+		}
+		query += orderBy
+	}
+	if nil != pageable {
+		query += fmt.Sprintf(" LIMIT %s OFFSET %s", r.dialect.Placeholder(len(args)+1), r.dialect.Placeholder(len(args)+2))
+		args = append(args, pageable.GetSize(), pageable.GetOffset())
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*Entity, 0)
+	for rows.Next() {
+		var item Entity
+		v := reflect.ValueOf(&item).Elem()
+		dest := make([]interface{}, len(tb.columns))
+		for i, c := range tb.columns {
+			dest[i] = v.FieldByIndex(c.index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// orderBy 生成 ORDER BY 子句，只允许实体中已映射的属性，避免注入
+func (r *Repository[Entity]) orderBy(tb *table, s sort.Sort) (string, error) {
+	orders := s.GetOrders()
+	if len(orders) == 0 {
+		return "", nil
+	}
+	parts := make([]string, 0, len(orders))
+	for _, order := range orders {
+		c, ok := tb.byProperty[order.Property]
+		if !ok {
+			return "", fmt.Errorf("sql: unknown sort property %s", order.Property)
+		}
+		direction := "ASC"
+		if strings.ToLower(string(order.Direction)) == string(sort.DESC) {
+			direction = "DESC"
+		}
+		parts = append(parts, r.dialect.Quote(c.name)+" "+direction)
+	}
+	return " ORDER BY " + strings.Join(parts, ", "), nil
+}
+
+// save 主键为零值时插入（整数主键由数据库生成），否则通过方言的 upsert 语句原子地插入或更新
+func (r *Repository[Entity]) save(ctx context.Context, exec executor, entity *Entity) error {
+	tb, err := r.table()
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(entity).Elem()
+	pk := v.FieldByIndex(tb.pk.index)
+
+	if pk.IsZero() && !tb.pk.autoIncrement {
+		return fmt.Errorf("sql: primary key %s of %s is required", tb.pk.name, r.tableName)
+	}
+	return r.insert(ctx, exec, tb, v)
+}
+
+// insert 主键已赋值时冲突则更新其他列
+func (r *Repository[Entity]) insert(ctx context.Context, exec executor, tb *table, v reflect.Value) error {
+	pk := v.FieldByIndex(tb.pk.index)
+	generated := pk.IsZero()
+
+	var names, placeholders, updates []string
+	var args []interface{}
+	for _, c := range tb.columns {
+		if c == tb.pk && generated {
+			continue
+		}
+		args = append(args, v.FieldByIndex(c.index).Interface())
+		names = append(names, r.dialect.Quote(c.name))
+		placeholders = append(placeholders, r.dialect.Placeholder(len(args)))
+		if c != tb.pk {
+			updates = append(updates, r.dialect.Quote(c.name))
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		r.dialect.Quote(r.tableName), strings.Join(names, ", "), strings.Join(placeholders, ", "))
+
+	if !generated {
+		_, err := exec.ExecContext(ctx, query+r.dialect.Upsert(r.dialect.Quote(tb.pk.name), updates), args...)
+		return err
+	}
+
+	if returning := r.dialect.Returning(tb.pk.name); "" != returning {
+		return exec.QueryRowContext(ctx, query+returning, args...).Scan(pk.Addr().Interface())
+	}
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if pk.CanInt() {
+		pk.SetInt(id)
+	} else if pk.CanUint() {
+		pk.SetUint(uint64(id))
+	} else {
+		return errors.New("sql: unsupported generated primary key type")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aomi-go/data/common"
+	"github.com/aomi-go/data/common/page"
+	"github.com/aomi-go/data/common/sort"
+	"github.com/aomi-go/data/repository/criteria"
+	_ "modernc.org/sqlite"
+)
+
+type Report struct {
+	ID     int64  `db:"id"`
+	Name   string `db:"name"`
+	Amount int    `db:"amount"`
+	Ignore string `db:"-"`
+}
+
+func newTestRepository(t *testing.T) *Repository[Report] {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE report (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, amount INTEGER NOT NULL)`)
+	if err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	return NewRepositoryWithEntity[Report](db, SQLite, Report{})
+}
+
+func TestSaveAndFind(t *testing.T) {
+	ctx := context.TODO()
+	repo := newTestRepository(t)
+
+	saved, err := repo.Save(ctx, &Report{Name: "a", Amount: 1})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if saved.ID == 0 {
+		t.Fatalf("Save() did not assign an id")
+	}
+
+	saved.Amount = 5
+	if _, err := repo.Save(ctx, saved); err != nil {
+		t.Fatalf("Save() update error = %v", err)
+	}
+	found, err := repo.FindById(ctx, saved.ID)
+	if err != nil || found.Amount != 5 {
+		t.Fatalf("FindById() = %+v, %v", found, err)
+	}
+
+	if _, err := repo.FindById(ctx, 999); !errors.Is(err, common.ErrNoResult) {
+		t.Errorf("FindById() error = %v, want ErrNoResult", err)
+	}
+	if ok, _ := repo.DeleteById(ctx, saved.ID); !ok {
+		t.Errorf("DeleteById() = false")
+	}
+	if ok, _ := repo.ExistsById(ctx, saved.ID); ok {
+		t.Errorf("ExistsById() after delete = true")
+	}
+}
+
+func TestQueryWithPage(t *testing.T) {
+	ctx := context.TODO()
+	repo := newTestRepository(t)
+
+	var reports []*Report
+	for i := 0; i < 5; i++ {
+		reports = append(reports, &Report{Name: fmt.Sprintf("r-%d", i), Amount: i})
+	}
+	if _, err := repo.SaveMany(ctx, reports); err != nil {
+		t.Fatalf("SaveMany() error = %v", err)
+	}
+
+	p, err := repo.QueryWithPage(ctx, Where("amount >= ?", 1), page.NewPageableWithSort(0, 3, sort.NewSortBy(sort.DESC, "amount")))
+	if err != nil {
+		t.Fatalf("QueryWithPage() error = %v", err)
+	}
+	if p.TotalElements != 4 || p.TotalPages != 2 || len(p.Content) != 3 || p.Content[0].Amount != 4 {
+		t.Fatalf("QueryWithPage() = %+v", p)
+	}
+
+	data, _ := json.Marshal(p)
+	var shape map[string]interface{}
+	_ = json.Unmarshal(data, &shape)
+	for _, key := range []string{"content", "totalElements", "totalPages", "number", "size"} {
+		if _, ok := shape[key]; !ok {
+			t.Errorf("page json missing %s", key)
+		}
+	}
+
+	if _, err := repo.QueryWithSort(ctx, nil, &sort.Sort{Sort: "amount;drop table report,desc"}); err == nil {
+		t.Errorf("QueryWithSort() expected error for unknown property")
+	}
+
+	cond, err := WhereCriteria(SQLite, criteria.And(criteria.Between("amount", 1, 3), criteria.Like("name", "R-")))
+	if err != nil {
+		t.Fatalf("WhereCriteria() error = %v", err)
+	}
+	if count, err := repo.Count(ctx, cond); err != nil || count != 3 {
+		t.Errorf("Count() with criteria = %d, %v", count, err)
+	}
+
+	all, err := repo.FindAllById(ctx, reports[0].ID, reports[1].ID)
+	if err != nil || len(all) != 2 {
+		t.Errorf("FindAllById() = %d, %v", len(all), err)
+	}
+}
+
+func TestSaveWithId(t *testing.T) {
+	ctx := context.TODO()
+	repo := newTestRepository(t)
+
+	if _, err := repo.Save(ctx, &Report{ID: 7, Name: "a", Amount: 1}); err != nil {
+		t.Fatalf("Save() insert with id error = %v", err)
+	}
+	if _, err := repo.Save(ctx, &Report{ID: 7, Name: "b", Amount: 2}); err != nil {
+		t.Fatalf("Save() upsert error = %v", err)
+	}
+	found, err := repo.FindById(ctx, 7)
+	if err != nil || found.Name != "b" || found.Amount != 2 {
+		t.Fatalf("FindById() = %+v, %v", found, err)
+	}
+	if count, _ := repo.Count(ctx, nil); count != 1 {
+		t.Errorf("Count() = %d, want 1", count)
+	}
+}
+
+func TestDialectUpsert(t *testing.T) {
+	columns := []string{"`name`", "`amount`"}
+	if got, want := MySQL.Upsert("`id`", columns), " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `amount` = VALUES(`amount`)"; got != want {
+		t.Errorf("MySQL.Upsert() = %s, want %s", got, want)
+	}
+	if got, want := Postgres.Upsert(`"id"`, []string{`"name"`}), ` ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`; got != want {
+		t.Errorf("Postgres.Upsert() = %s, want %s", got, want)
+	}
+	if got, want := SQLite.Upsert(`"id"`, nil), ` ON CONFLICT ("id") DO NOTHING`; got != want {
+		t.Errorf("SQLite.Upsert() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// TableEntity 实现该接口的实体使用 TableName 作为表名
+type TableEntity interface {
+	TableName() string
+}
+
+// table 实体与表结构的映射
+type table struct {
+	name    string
+	columns []*column
+	pk      *column
+	// byProperty 属性名（字段名或列名）到列的映射，用于排序
+	byProperty map[string]*column
+}
+
+type column struct {
+	name          string
+	index         []int
+	autoIncrement bool
+}
+
+var tables sync.Map
+
+// tableOf 解析实体的列映射，标签格式为 db:"column[,pk]"，db:"-" 表示忽略该字段。
+// 未声明 pk 时使用名为 id 的列作为主键，整数主键视为自增。
+func tableOf(t reflect.Type) (*table, error) {
+	if v, ok := tables.Load(t); ok {
+		return v.(*table), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sql: entity %s must be a struct", t)
+	}
+
+	tb := &table{byProperty: map[string]*column{}}
+	collectColumns(tb, t, nil)
+	if nil == tb.pk {
+		for _, c := range tb.columns {
+			if c.name == "id" {
+				tb.pk = c
+				break
+			}
+		}
+	}
+	if nil == tb.pk {
+		return nil, fmt.Errorf("sql: entity %s has no primary key", t)
+	}
+	switch t.FieldByIndex(tb.pk.index).Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		tb.pk.autoIncrement = true
+	}
+	tb.byProperty["id"] = tb.pk
+
+	v, _ := tables.LoadOrStore(t, tb)
+	return v.(*table), nil
+}
+
+func collectColumns(tb *table, t reflect.Type, parent []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && !hasTag && f.Type.Kind() == reflect.Struct {
+			collectColumns(tb, f.Type, index)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		if "" == name {
+			name = toSnakeCase(f.Name)
+		}
+		c := &column{name: name, index: index}
+		for _, opt := range parts[1:] {
+			if strings.TrimSpace(opt) == "pk" {
+				tb.pk = c
+			}
+		}
+		tb.columns = append(tb.columns, c)
+		tb.byProperty[name] = c
+		tb.byProperty[f.Name] = c
+	}
+}
+
+// tableName 获取实体对应的表名，实现 TableEntity 时使用其返回值，否则为结构体名称的 snake_case 形式
+func tableName(emptyEntity any) string {
+	if v, ok := emptyEntity.(TableEntity); ok {
+		return v.TableName()
+	}
+	entityType := reflect.TypeOf(emptyEntity)
+	if entityType.Kind() == reflect.Ptr {
+		entityType = entityType.Elem()
+	}
+	return toSnakeCase(entityType.Name())
+}
+
+var (
+	matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
+	matchAllCap   = regexp.MustCompile("([a-z0-9])([A-Z])")
+)
+
+// toSnakeCase converts a CamelCase string to snake_case.
+func toSnakeCase(str string) string {
+	snake := matchFirstCap.ReplaceAllString(str, "${1}_${2}")
+	snake = matchAllCap.ReplaceAllString(snake, "${1}_${2}")
+	return strings.ToLower(snake)
+}